@@ -1,28 +1,48 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
-	arista "netmetrics_exporter/internal/collector/arista"
-	"netmetrics_exporter/internal/collector/cisco"
-	"netmetrics_exporter/internal/collector/nokia"
+	"netmetrics_exporter/internal/collector"
+	_ "netmetrics_exporter/internal/collector/all"
 	"netmetrics_exporter/internal/inventory"
 	"netmetrics_exporter/internal/metrics"
+	"netmetrics_exporter/internal/probe"
+	"netmetrics_exporter/internal/scheduler"
 	"netmetrics_exporter/internal/version"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	inventoryPath := flag.String("inventory", "configs/inventory.yaml", "Path to inventory YAML file")
+	inventoryPath := flag.String("inventory", "configs/inventory.yaml", "Path to inventory file")
+	inventoryFormat := flag.String("inventory-format", inventory.FormatAuto, "Inventory format: "+strings.Join(inventory.Formats, ", "))
 	listenAddress := flag.String("listen-address", ":9200", "Address to expose /metrics")
+	pollInterval := flag.Duration("poll-interval", 30*time.Second, "Default interval between two polls of the same device")
+	pollTimeout := flag.Duration("poll-timeout", 20*time.Second, "Default timeout for a single device poll")
+	pollJitter := flag.Duration("poll-jitter", 5*time.Second, "Maximum random delay added to every poll interval")
+	pollWorkers := flag.Int("poll-workers", 16, "Maximum number of devices polled concurrently")
+	vaultPasswordFile := flag.String("vault-password-file", os.Getenv("ANSIBLE_VAULT_PASSWORD_FILE"), "File holding the Ansible Vault password, or an executable printing it (default $ANSIBLE_VAULT_PASSWORD_FILE; $NETMETRICS_VAULT_PASSWORD also works)")
+	watchInterval := flag.Duration("inventory-watch-interval", 30*time.Second, "How often to check the inventory file for changes (0 disables watching)")
+	listCollectors := flag.Bool("list-collectors", false, "Print the available collectors and exit")
 	flag.Parse()
 
+	if *listCollectors {
+		printCollectors(os.Stdout)
+		return
+	}
+
 	// Pretty banner
 	fmt.Println("===================================")
 	fmt.Printf("🛰️  netmetrics_exporter %s (commit %s, built at %s)\n", version.Version, version.Commit, version.BuildDate)
@@ -32,46 +52,124 @@ func main() {
 	// Register Prometheus collectors
 	metrics.Register()
 
-	// Load inventory
-	var devices []inventory.Device
-	if strings.Contains(*inventoryPath, "ansible") {
-		devices = inventory.LoadAnsibleYAML(*inventoryPath)
-	} else {
-		devices = inventory.Load(*inventoryPath)
-	}
+	// Start background collection
+	poll := func(ctx context.Context, dev inventory.Device) error {
+		// Series the cycle does not refresh (removed interfaces, dropped
+		// peers, old duplex values) are deleted once it ends.
+		metrics.Default.BeginCycle(dev.Hostname)
+		defer metrics.Default.EndCycle(dev.Hostname)
 
-	// Debug: print loaded devices
-	for _, dev := range devices {
-		log.Printf("[DEBUG] Loaded Device: Hostname=%s IP=%s Vendor=%s Protocol=%s",
-			dev.Hostname, dev.IP, dev.Vendor, dev.Protocol)
+		start := time.Now()
+		err := collect(ctx, dev, metrics.Default)
+		metrics.Default.ObserveDevice(dev.Hostname, dev.Vendor, start, err)
+		return err
 	}
+	sched := scheduler.New(scheduler.Config{
+		Workers:  *pollWorkers,
+		Interval: *pollInterval,
+		Jitter:   *pollJitter,
+		Timeout:  *pollTimeout,
+		OnStop: func(dev inventory.Device) {
+			metrics.Default.DeleteDevice(dev.Hostname)
+		},
+	}, poll)
 
-	// Start background collection loop
-	go func() {
-		for {
-			for _, dev := range devices {
-				var err error
-
-				switch dev.Vendor {
-				case "arista":
-					err = arista.AristaCollector{}.Collect(dev)
+	// Load inventory
+	opts := inventory.LoadOptions{Format: *inventoryFormat}
+	if *vaultPasswordFile != "" {
+		password, err := inventory.ReadVaultPasswordFile(*vaultPasswordFile)
+		if err != nil {
+			log.Fatalf("Failed to read vault password: %v", err)
+		}
+		opts.VaultPassword = password
+	} else if password := os.Getenv("NETMETRICS_VAULT_PASSWORD"); password != "" {
+		opts.VaultPassword = []byte(password)
+	}
+	inv := &reloader{path: *inventoryPath, opts: opts, sched: sched}
+	if err := inv.reload(); err != nil {
+		log.Fatalf("Failed to load inventory: %v", err)
+	}
 
-				case "srlinux":
-					err = nokia.SRLinuxCollector{}.Collect(dev)
+	// Cancelling ctx stops the pollers and aborts any in-flight device calls.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-				case "cisco":
-					log.Printf("[INFO] ▶️  Calling CollectorCSR for %s", dev.Hostname)
-					err = cisco.CollectorCSR{}.Collect(dev)
-				}
+	polling := make(chan struct{})
+	go func() {
+		defer close(polling)
+		sched.Run(ctx)
+	}()
 
-				if err != nil {
-					log.Printf("[ERROR] %s (%s): %v", dev.Hostname, dev.Vendor, err)
-				}
+	// Reload the inventory on SIGHUP and whenever the file changes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				inv.reloadAndLog("SIGHUP")
 			}
-			time.Sleep(30 * time.Second)
 		}
 	}()
+	if *watchInterval > 0 {
+		go inventory.Watch(ctx, *inventoryPath, *watchInterval, func() {
+			inv.reloadAndLog("file changed")
+		})
+	}
 
 	http.Handle("/metrics", promhttp.Handler())
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	http.Handle("/-/reload", inv)
+	http.Handle("/probe", &probe.Handler{
+		Lookup:  inv.lookup,
+		Timeout: *pollTimeout,
+	})
+	srv := &http.Server{Addr: *listenAddress}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-polling
+	log.Printf("[INFO] Shut down cleanly")
+}
+
+// collect runs the registered collector that matches dev.
+func collect(ctx context.Context, dev inventory.Device, m *metrics.Set) error {
+	c, err := collector.For(dev)
+	if err != nil {
+		return err
+	}
+	return c.Collect(ctx, dev, m)
+}
+
+// printCollectors writes the collector registry as a table, with the
+// ansible_network_os values that select each vendor.
+func printCollectors(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VENDOR\tPROTOCOL\tNETWORK_OS\tCAPABILITIES")
+	seen := make(map[string]bool)
+	for _, r := range collector.Registrations() {
+		protocol := r.Protocol
+		if !seen[r.Vendor] {
+			protocol += " (default)"
+			seen[r.Vendor] = true
+		}
+		vendor, networkOS := r.Vendor, "*"
+		if vendor == "" {
+			// Generic collectors serve any vendor that selects them
+			// with netmetrics_protocol.
+			vendor, protocol = "*", r.Protocol
+		} else {
+			networkOS = strings.Join(append([]string{r.Vendor}, inventory.Aliases(r.Vendor)...), ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", vendor, protocol, networkOS, strings.Join(r.Capabilities, ","))
+	}
+	w.Flush()
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+	"netmetrics_exporter/internal/scheduler"
+)
+
+// reloader owns the active inventory. Every reload is parsed and validated
+// before it replaces the previous one, so a broken file keeps the exporter
+// polling the last good inventory.
+type reloader struct {
+	path  string
+	opts  inventory.LoadOptions
+	sched *scheduler.Scheduler
+
+	mu      sync.Mutex
+	devices []inventory.Device
+}
+
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices, err := inventory.LoadFile(r.path, r.opts)
+	if err != nil {
+		metrics.InventoryReloadSuccess.Set(0)
+		return err
+	}
+
+	// Broken entries are skipped on their own so they cannot hold back the
+	// rest of the inventory.
+	devices, invalid := inventory.Validate(devices)
+	for _, err := range invalid {
+		log.Printf("[ERROR] Skipping inventory entry in %s: %v", r.path, err)
+	}
+
+	// Devices no collector can poll stay available to /probe, which may
+	// pick another module, but are not scheduled.
+	metrics.InventoryUnsupportedDevices.Reset()
+	var polled []inventory.Device
+	for i, dev := range devices {
+		reg, err := collector.Lookup(dev.Vendor, dev.Protocol)
+		if err != nil {
+			log.Printf("[ERROR] Device %s is not polled: %v", dev.Hostname, err)
+			metrics.InventoryUnsupportedDevices.WithLabelValues(dev.Hostname, dev.Vendor, dev.Protocol).Set(1)
+			continue
+		}
+		devices[i].Protocol = reg.Protocol
+		polled = append(polled, devices[i])
+		log.Printf("[DEBUG] Loaded Device: Hostname=%s IP=%s Vendor=%s Protocol=%s",
+			dev.Hostname, dev.IP, dev.Vendor, reg.Protocol)
+	}
+
+	r.devices = devices
+	r.sched.Update(polled)
+
+	metrics.InventoryDevices.Set(float64(len(devices)))
+	metrics.InventoryInvalidDevices.Set(float64(len(invalid)))
+	metrics.InventoryReloadSuccess.Set(1)
+	metrics.InventoryReloadTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// reloadAndLog is the fire-and-forget variant used by SIGHUP and the file
+// watcher.
+func (r *reloader) reloadAndLog(reason string) {
+	if err := r.reload(); err != nil {
+		log.Printf("[ERROR] Inventory reload (%s) failed, keeping previous inventory: %v", reason, err)
+		return
+	}
+	log.Printf("[INFO] Inventory reloaded (%s)", reason)
+}
+
+// lookup resolves a /probe target by hostname or management IP.
+func (r *reloader) lookup(target string) (inventory.Device, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, dev := range r.devices {
+		if dev.Hostname == target || dev.IP == target {
+			return dev, true
+		}
+	}
+	return inventory.Device{}, false
+}
+
+// ServeHTTP handles POST /-/reload, like Prometheus' own lifecycle endpoint.
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "Only POST or PUT requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.reload(); err != nil {
+		log.Printf("[ERROR] Inventory reload (HTTP) failed, keeping previous inventory: %v", err)
+		http.Error(w, fmt.Sprintf("failed to reload inventory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Inventory reloaded (HTTP)")
+	fmt.Fprintln(w, "OK")
+}
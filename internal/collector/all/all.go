@@ -0,0 +1,13 @@
+// Package all imports every vendor collector so each registers itself with
+// the collector registry. A new vendor package only needs a line here.
+package all
+
+import (
+	_ "netmetrics_exporter/internal/collector/arista"
+	_ "netmetrics_exporter/internal/collector/cisco"
+	_ "netmetrics_exporter/internal/collector/cumulus"
+	_ "netmetrics_exporter/internal/collector/gnmi"
+	_ "netmetrics_exporter/internal/collector/juniper"
+	_ "netmetrics_exporter/internal/collector/nokia"
+	_ "netmetrics_exporter/internal/collector/snmp"
+)
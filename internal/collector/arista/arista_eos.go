@@ -2,51 +2,91 @@ package arista
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 
+	"netmetrics_exporter/internal/collector"
 	"netmetrics_exporter/internal/inventory"
 	"netmetrics_exporter/internal/metrics"
 )
 
 type AristaCollector struct{}
 
-func (c AristaCollector) Collect(device inventory.Device) error {
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "arista",
+		Protocol: "eapi",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapInterfaceCounters,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapISIS,
+			collector.CapLLDP,
+			collector.CapTransceivers,
+			collector.CapEnvironment,
+			collector.CapCPU,
+			collector.CapMemory,
+		},
+		New: func() collector.Collector { return AristaCollector{} },
+	})
+}
+
+func (c AristaCollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
 	// Try to enable eAPI via SSH (non-fatal)
-	if err := ensureEAPIEnabled(device); err != nil {
+	if err := ensureEAPIEnabled(ctx, device); err != nil {
 		fmt.Printf("⚠️  Skipping eAPI enable for %s (%s): %v\n", device.Hostname, device.IP, err)
 	}
 
-	// Fetch metrics via JSON-RPC
-	result, err := runEAPI(device, []string{
-		"show interfaces status",
-		"show ip bgp summary",
-		"show version",
-		"show ip ospf neighbor",
-		"show interfaces counters errors",
-		"show lldp neighbors",
-	})
+	// Every section issues its own eAPI call, so one failing command only
+	// costs that section. "show version" doubles as the reachability check.
+
+	// 1) Version, uptime & memory; both sections are timed from the one
+	// "show version" call they share
+	start := time.Now()
+	verBlock, err := runCmd(ctx, device, "show version")
+	m.ObserveSection(device.Hostname, "system", start, err)
 	if err != nil {
+		m.ObserveSection(device.Hostname, "memory", start, err)
 		return err
 	}
-
-	if os.Getenv("NETMETRICS_DEBUG") == "1" {
-		fmt.Printf("DEBUG JSON RESULT from %s: %+v\n", device.Hostname, result)
+	if uptime, ok := verBlock["uptime"].(float64); ok {
+		m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime)
+	}
+	if model, ok := verBlock["modelName"].(string); ok {
+		if ver, ok := verBlock["version"].(string); ok {
+			m.DeviceInfo.WithLabelValues(device.Hostname, model, ver).Set(1)
+		}
+	}
+	// Memory is reported in kB
+	total, totalOK := verBlock["memTotal"].(float64)
+	free, freeOK := verBlock["memFree"].(float64)
+	if totalOK && freeOK {
+		m.SetMemory(device.Hostname, device.Vendor, total*1024, (total-free)*1024)
+	} else {
+		err = fmt.Errorf("show version: no memTotal/memFree")
 	}
+	m.ObserveSection(device.Hostname, "memory", start, err)
 
-	// 1) Interfaces
-	if len(result) > 0 {
-		ifaces, _ := result[0]["interfaceStatuses"].(map[string]interface{})
+	// 2) Interfaces
+	start = time.Now()
+	status, err := runCmd(ctx, device, "show interfaces status")
+	if err == nil {
+		ifaces, _ := status["interfaceStatuses"].(map[string]interface{})
 		for name, raw := range ifaces {
 			d := raw.(map[string]interface{})
 
@@ -54,114 +94,518 @@ func (c AristaCollector) Collect(device inventory.Device) error {
 			if s, _ := d["lineProtocolStatus"].(string); s == "connected" {
 				up = 1
 			}
-			metrics.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
 
 			if bw, ok := d["bandwidth"].(float64); ok {
-				metrics.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(bw / 1_000_000)
+				m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(bw / 1_000_000)
 			} else {
-				metrics.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(-1)
+				m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(-1)
 			}
 
 			duplex := "unknown"
 			if dpx, ok := d["duplex"].(string); ok {
 				duplex = dpx
 			}
-			metrics.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
 		}
 	}
-
-	// 2) BGP peers
-	if len(result) > 1 {
-		if vrfs, ok := result[1]["vrfs"].(map[string]interface{}); ok {
-			if def, ok := vrfs["default"].(map[string]interface{}); ok {
-				if peers, ok := def["peers"].(map[string]interface{}); ok {
-					metrics.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+
+	// 3) BGP sessions in every VRF
+	start = time.Now()
+	peers, err := bgpPeers(ctx, device)
+	if peers != nil {
+		for _, peer := range peers {
+			m.SetBGPPeer(device.Hostname, *peer)
+		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
+	}
+	m.ObserveSection(device.Hostname, "bgp", start, err)
+
+	// 4) OSPF adjacencies in every VRF and instance
+	start = time.Now()
+	ospf, err := runCmd(ctx, device, "show ip ospf neighbor detail vrf all")
+	if err == nil {
+		now := float64(time.Now().Unix())
+		neighbors := 0
+		vrfs, _ := ospf["vrfs"].(map[string]interface{})
+		for vrf, raw := range vrfs {
+			v, _ := raw.(map[string]interface{})
+			instances, _ := v["instList"].(map[string]interface{})
+			for instance, raw := range instances {
+				inst, _ := raw.(map[string]interface{})
+				entries, _ := inst["ospfNeighborEntries"].([]interface{})
+				for _, raw := range entries {
+					d, _ := raw.(map[string]interface{})
+					details, _ := d["details"].(map[string]interface{})
+					n := metrics.OSPFNeighbor{
+						VRF:           vrf,
+						Instance:      instance,
+						RouterID:      str(d["routerId"]),
+						Address:       str(d["interfaceAddress"]),
+						Interface:     str(d["interfaceName"]),
+						Area:          str(details["areaId"]),
+						State:         str(d["adjacencyState"]),
+						UptimeSeconds: -1,
+					}
+					if since, ok := details["stateTime"].(float64); ok {
+						n.UptimeSeconds = now - since
+					}
+					m.SetOSPFNeighbor(device.Hostname, n)
+					neighbors++
 				}
 			}
 		}
-	} else {
-		metrics.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(0)
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
 	}
+	m.ObserveSection(device.Hostname, "ospf", start, err)
 
-	// 3) Version & uptime
-	if len(result) > 2 {
-		verBlock := result[2]
-		if uptime, ok := verBlock["uptime"].(float64); ok {
-			metrics.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime)
+	// 5) Interface error counters
+	start = time.Now()
+	errCounters, err := runCmd(ctx, device, "show interfaces counters errors")
+	if err == nil {
+		if counters, ok := errCounters["interfaceCounters"].(map[string]interface{}); ok {
+			for iface, raw := range counters {
+				d := raw.(map[string]interface{})
+				inE, _ := d["inputErrors"].(float64)
+				outE, _ := d["outputErrors"].(float64)
+				m.InterfaceInputErrors.WithLabelValues(device.Hostname, iface).Set(inE)
+				m.InterfaceOutputErrors.WithLabelValues(device.Hostname, iface).Set(outE)
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	// 6) Interface traffic counters
+	start = time.Now()
+	trafficCounters, err := runCmd(ctx, device, "show interfaces counters")
+	if err == nil {
+		if counters, ok := trafficCounters["interfaces"].(map[string]interface{}); ok {
+			for iface, raw := range counters {
+				d, _ := raw.(map[string]interface{})
+				get := func(key string) float64 {
+					v, _ := d[key].(float64)
+					return v
+				}
+				m.SetInterfaceCounters(device.Hostname, iface, metrics.InterfaceCounters{
+					InOctets:            get("inOctets"),
+					OutOctets:           get("outOctets"),
+					InUnicastPackets:    get("inUcastPkts"),
+					OutUnicastPackets:   get("outUcastPkts"),
+					InMulticastPackets:  get("inMulticastPkts"),
+					OutMulticastPackets: get("outMulticastPkts"),
+					InBroadcastPackets:  get("inBroadcastPkts"),
+					OutBroadcastPackets: get("outBroadcastPkts"),
+					InDiscards:          get("inDiscards"),
+					OutDiscards:         get("outDiscards"),
+				})
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interface_counters", start, err)
+
+	// 7) LLDP neighbors
+	start = time.Now()
+	lldpResp, err := runCmd(ctx, device, "show lldp neighbors")
+	if err == nil {
+		if lldp, ok := lldpResp["lldpNeighbors"].([]interface{}); ok {
+			m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(lldp)))
+		} else {
+			m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(-1)
+		}
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	// 8) IS-IS adjacencies and LSP database in every instance
+	start = time.Now()
+	isis, err := runCmd(ctx, device, "show isis neighbors detail")
+	if err == nil {
+		now := float64(time.Now().Unix())
+		adjacencies := 0
+		forEachISISInstance(isis, func(instance string, inst map[string]interface{}) {
+			neighbors, _ := inst["neighbors"].(map[string]interface{})
+			for systemID, raw := range neighbors {
+				nbr, _ := raw.(map[string]interface{})
+				list, _ := nbr["adjacencies"].([]interface{})
+				for _, raw := range list {
+					d, _ := raw.(map[string]interface{})
+					details, _ := d["details"].(map[string]interface{})
+					adj := metrics.ISISAdjacency{
+						Instance:        instance,
+						SystemID:        systemID,
+						Interface:       str(d["interfaceName"]),
+						Level:           str(d["level"]),
+						State:           str(d["state"]),
+						HoldTimeSeconds: number(d["holdRemaining"]),
+						UptimeSeconds:   -1,
+					}
+					if since, ok := details["stateChanged"].(float64); ok {
+						adj.UptimeSeconds = now - since
+					}
+					m.SetISISAdjacency(device.Hostname, adj)
+					adjacencies++
+				}
+			}
+		})
+		m.ISISAdjacencies.WithLabelValues(device.Hostname, device.Vendor).Set(float64(adjacencies))
+
+		var db map[string]interface{}
+		if db, err = runCmd(ctx, device, "show isis database"); err == nil {
+			forEachISISInstance(db, func(instance string, inst map[string]interface{}) {
+				levels, _ := inst["level"].(map[string]interface{})
+				for level, raw := range levels {
+					l, _ := raw.(map[string]interface{})
+					lsps, _ := l["lsps"].(map[string]interface{})
+					m.ISISLSPDatabaseLSPs.WithLabelValues(device.Hostname, instance, metrics.ISISLevel(level)).Set(float64(len(lsps)))
+				}
+			})
+		}
+	}
+	m.ObserveSection(device.Hostname, "isis", start, err)
+
+	// 9) Optical transceivers
+	start = time.Now()
+	xcvrs, err := runCmd(ctx, device, "show interfaces transceiver detail")
+	if err == nil {
+		// The inventory names the optic in every transceiver slot
+		slots := map[string]interface{}{}
+		if inv, err := runCmd(ctx, device, "show inventory"); err == nil {
+			slots, _ = inv["xcvrSlots"].(map[string]interface{})
+		}
+		ifaces, _ := xcvrs["interfaces"].(map[string]interface{})
+		for name, raw := range ifaces {
+			d, _ := raw.(map[string]interface{})
+			xcvr := metrics.Transceiver{
+				Interface:          name,
+				TemperatureCelsius: reading(d["temperature"]),
+				VoltageVolts:       reading(d["voltage"]),
+				Lanes: []metrics.TransceiverLane{{
+					Lane:             "1",
+					RxPowerDBm:       reading(d["rxPower"]),
+					TxPowerDBm:       reading(d["txPower"]),
+					BiasMilliamperes: reading(d["txBias"]),
+				}},
+			}
+			if slot, ok := slots[xcvrSlot(name)].(map[string]interface{}); ok {
+				xcvr.Manufacturer = str(slot["mfgName"])
+				xcvr.PartNumber = str(slot["modelName"])
+				xcvr.Serial = str(slot["serialNum"])
+			}
+			details, _ := d["details"].(map[string]interface{})
+			for field, sensor := range map[string]string{
+				"rxPower":     "rx_power",
+				"txPower":     "tx_power",
+				"txBias":      "bias_current",
+				"temperature": "temperature",
+				"voltage":     "voltage",
+			} {
+				thresholds, _ := details[field].(map[string]interface{})
+				for limit, level := range map[string]string{
+					"highAlarm": "high_alarm",
+					"highWarn":  "high_warning",
+					"lowWarn":   "low_warning",
+					"lowAlarm":  "low_alarm",
+				} {
+					if v, ok := thresholds[limit].(float64); ok {
+						xcvr.Thresholds = append(xcvr.Thresholds, metrics.TransceiverThreshold{Sensor: sensor, Level: level, Value: v})
+					}
+				}
+			}
+			m.SetTransceiver(device.Hostname, xcvr)
 		}
-		if model, ok := verBlock["modelName"].(string); ok {
-			if ver, ok := verBlock["version"].(string); ok {
-				metrics.DeviceInfo.WithLabelValues(device.Hostname, model, ver).Set(1)
+	}
+	m.ObserveSection(device.Hostname, "transceivers", start, err)
+
+	// 10) Environment: temperatures, fans, power supplies and modules
+	start = time.Now()
+	err = environment(ctx, device, m)
+	m.ObserveSection(device.Hostname, "environment", start, err)
+
+	// 11) CPU: overall and per-core usage, load averages. EOS has no
+	// averaged usage percentage; loadAvg is the run-queue length.
+	start = time.Now()
+	top, err := runCmd(ctx, device, "show processes top once")
+	if err == nil {
+		cpus, _ := top["cpuInfo"].(map[string]interface{})
+		for name, raw := range cpus {
+			d, _ := raw.(map[string]interface{})
+			idle, ok := d["idle"].(float64)
+			if !ok {
+				continue
+			}
+			if name == "%Cpu(s)" {
+				m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(100 - idle)
+			} else if core := strings.TrimPrefix(name, "%Cpu"); core != name {
+				m.CPUCoreUsage.WithLabelValues(device.Hostname, device.Vendor, core).Set(100 - idle)
+			}
+		}
+		timeInfo, _ := top["timeInfo"].(map[string]interface{})
+		loads, _ := timeInfo["loadAvg"].([]interface{})
+		for i, window := range []string{"1m", "5m", "15m"} {
+			if i < len(loads) {
+				if load, ok := loads[i].(float64); ok {
+					m.CPULoadAverage.WithLabelValues(device.Hostname, device.Vendor, window).Set(load)
+				}
 			}
 		}
 	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
 
-	// 4) OSPF neighbors
-	if len(result) > 3 {
-		ospfCount := -1.0
-		if vrfs, ok := result[3]["vrfs"].(map[string]interface{}); ok {
-			if def, ok := vrfs["default"].(map[string]interface{}); ok {
-				if instList, ok := def["instList"].(map[string]interface{}); ok {
-					if inst, ok := instList["1"].(map[string]interface{}); ok {
-						if entries, ok := inst["ospfNeighborEntries"].([]interface{}); ok {
-							ospfCount = float64(len(entries))
-						}
+	return ctx.Err()
+}
+
+// bgpPeers reads every BGP session, keyed by VRF and peer address. Each
+// address family's summary gives the state and prefix counts, and its
+// neighbor detail adds flaps and advertised prefixes. Only a failed IPv4
+// summary loses the sessions: the IPv6 commands fail on switches without
+// IPv6 BGP and on older EOS, so that family is then left out, and a failed
+// detail keeps the summary's sessions but is still returned as the error.
+func bgpPeers(ctx context.Context, device inventory.Device) (map[string]*metrics.BGPPeer, error) {
+	peers := make(map[string]*metrics.BGPPeer)
+	now := float64(time.Now().Unix())
+	var detailErr error
+
+	for _, family := range []struct{ afiSafi, summary, detail string }{
+		{"ipv4-unicast", "show ip bgp summary vrf all", "show ip bgp neighbors vrf all"},
+		{"ipv6-unicast", "show ipv6 bgp summary vrf all", "show ipv6 bgp neighbors vrf all"},
+	} {
+		summary, err := runCmd(ctx, device, family.summary)
+		if err != nil {
+			if family.afiSafi == "ipv4-unicast" {
+				return nil, err
+			}
+			continue
+		}
+		vrfs, _ := summary["vrfs"].(map[string]interface{})
+		for vrf, raw := range vrfs {
+			v, _ := raw.(map[string]interface{})
+			list, _ := v["peers"].(map[string]interface{})
+			for addr, raw := range list {
+				d, _ := raw.(map[string]interface{})
+				peer, ok := peers[vrf+"|"+addr]
+				if !ok {
+					peer = &metrics.BGPPeer{VRF: vrf, Address: addr, RemoteAS: asString(d["asn"]), EstablishedSeconds: -1, Flaps: -1}
+					peers[vrf+"|"+addr] = peer
+				}
+				peer.State, _ = d["peerState"].(string)
+				if changed, ok := d["upDownTime"].(float64); ok {
+					peer.EstablishedSeconds = now - changed
+				}
+				peer.Families = append(peer.Families, metrics.BGPFamily{
+					AFISAFI:    family.afiSafi,
+					Received:   number(d["prefixReceived"]),
+					Accepted:   number(d["prefixAccepted"]),
+					Advertised: -1,
+				})
+			}
+		}
+		if len(vrfs) == 0 {
+			continue
+		}
+
+		detail, err := runCmd(ctx, device, family.detail)
+		if err != nil {
+			if detailErr == nil {
+				detailErr = err
+			}
+			continue
+		}
+		vrfs, _ = detail["vrfs"].(map[string]interface{})
+		for vrf, raw := range vrfs {
+			v, _ := raw.(map[string]interface{})
+			list, _ := v["peerList"].([]interface{})
+			for _, raw := range list {
+				d, _ := raw.(map[string]interface{})
+				addr, _ := d["peerAddress"].(string)
+				peer, ok := peers[vrf+"|"+addr]
+				if !ok {
+					continue
+				}
+				if flaps := number(d["establishedTransitions"]); flaps >= 0 {
+					peer.Flaps = flaps
+				}
+				for i := range peer.Families {
+					if peer.Families[i].AFISAFI == family.afiSafi {
+						peer.Families[i].Advertised = number(d["prefixesSent"])
 					}
 				}
 			}
 		}
-		metrics.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(ospfCount)
 	}
+	return peers, detailErr
+}
 
-	// 5) Interface error counters
-	if len(result) > 4 {
-		if counters, ok := result[4]["interfaceCounters"].(map[string]interface{}); ok {
-			for iface, raw := range counters {
-				d := raw.(map[string]interface{})
-				inE, _ := d["inputErrors"].(float64)
-				outE, _ := d["outputErrors"].(float64)
-				metrics.InterfaceInputErrors.WithLabelValues(device.Hostname, iface).Set(inE)
-				metrics.InterfaceOutputErrors.WithLabelValues(device.Hostname, iface).Set(outE)
+// forEachISISInstance calls fn for every IS-IS instance in every VRF of an
+// IS-IS show command.
+func forEachISISInstance(resp map[string]interface{}, fn func(instance string, inst map[string]interface{})) {
+	vrfs, _ := resp["vrfs"].(map[string]interface{})
+	for _, raw := range vrfs {
+		v, _ := raw.(map[string]interface{})
+		instances, _ := v["isisInstances"].(map[string]interface{})
+		for name, raw := range instances {
+			inst, _ := raw.(map[string]interface{})
+			fn(name, inst)
+		}
+	}
+}
+
+// environment reads the "show environment" commands and the module table.
+func environment(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	temp, err := runCmd(ctx, device, "show environment temperature")
+	if err != nil {
+		return err
+	}
+	// Sensors sit on the chassis, on every card and in every power supply
+	sensors, _ := temp["tempSensors"].([]interface{})
+	for _, key := range []string{"cardSlots", "powerSupplySlots"} {
+		slots, _ := temp[key].([]interface{})
+		for _, raw := range slots {
+			slot, _ := raw.(map[string]interface{})
+			more, _ := slot["tempSensors"].([]interface{})
+			sensors = append(sensors, more...)
+		}
+	}
+	for _, raw := range sensors {
+		d, _ := raw.(map[string]interface{})
+		name := str(d["name"])
+		if cur, ok := d["currentTemperature"].(float64); ok {
+			m.TemperatureCelsius.WithLabelValues(device.Hostname, name).Set(cur)
+		}
+		if warn, ok := d["overheatThreshold"].(float64); ok {
+			m.TemperatureThreshold.WithLabelValues(device.Hostname, name, "warning").Set(warn)
+		}
+		if crit, ok := d["criticalThreshold"].(float64); ok {
+			m.TemperatureThreshold.WithLabelValues(device.Hostname, name, "critical").Set(crit)
+		}
+	}
+
+	cooling, err := runCmd(ctx, device, "show environment cooling")
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"fanTraySlots", "powerSupplySlots"} {
+		slots, _ := cooling[key].([]interface{})
+		for _, raw := range slots {
+			slot, _ := raw.(map[string]interface{})
+			fans, _ := slot["fans"].([]interface{})
+			for _, raw := range fans {
+				fan, _ := raw.(map[string]interface{})
+				name := str(fan["label"])
+				status := str(fan["status"])
+				if status == "notInserted" {
+					continue
+				}
+				m.FanOK.WithLabelValues(device.Hostname, name).Set(okStatus(status))
+				if speed, ok := fan["actualSpeed"].(float64); ok {
+					m.FanSpeedPercent.WithLabelValues(device.Hostname, name).Set(speed)
+				}
 			}
 		}
 	}
 
-	// 6) LLDP neighbors
-	if len(result) > 5 {
-		if lldp, ok := result[5]["lldpNeighbors"].([]interface{}); ok {
-			metrics.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(lldp)))
-		} else {
-			metrics.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(-1)
+	power, err := runCmd(ctx, device, "show environment power")
+	if err != nil {
+		return err
+	}
+	psus, _ := power["powerSupplies"].(map[string]interface{})
+	for slot, raw := range psus {
+		psu, _ := raw.(map[string]interface{})
+		status := str(psu["state"])
+		if status == "notInserted" {
+			continue
+		}
+		m.PSUOK.WithLabelValues(device.Hostname, slot).Set(okStatus(status))
+		if in, ok := psu["inputPower"].(float64); ok {
+			m.PSUInputPowerWatts.WithLabelValues(device.Hostname, slot).Set(in)
+		}
+		if out, ok := psu["outputPower"].(float64); ok {
+			m.PSUOutputPowerWatts.WithLabelValues(device.Hostname, slot).Set(out)
 		}
 	}
 
+	modules, err := runCmd(ctx, device, "show module")
+	if err != nil {
+		return err
+	}
+	list, _ := modules["modules"].(map[string]interface{})
+	for slot, raw := range list {
+		mod, _ := raw.(map[string]interface{})
+		up := 0.0
+		switch str(mod["status"]) {
+		case "ok", "active", "standby":
+			up = 1
+		}
+		m.ModuleUp.WithLabelValues(device.Hostname, slot).Set(up)
+	}
 	return nil
 }
 
+// okStatus maps the "ok" status of fans and power supplies to 1.
+func okStatus(status string) float64 {
+	if status == "ok" {
+		return 1
+	}
+	return 0
+}
+
+// xcvrSlot returns the transceiver slot of an interface, which is its port
+// number: "Ethernet49/1" sits in slot "49".
+func xcvrSlot(iface string) string {
+	port := strings.TrimPrefix(iface, "Ethernet")
+	if i := strings.Index(port, "/"); i >= 0 {
+		port = port[:i]
+	}
+	return port
+}
+
+// reading returns a numeric sensor reading, or NaN when it is missing.
+func reading(v interface{}) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return math.NaN()
+}
+
+// asString formats an AS number, which EOS reports as a string or, on older
+// releases, as a number.
+func asString(v interface{}) string {
+	switch as := v.(type) {
+	case string:
+		return as
+	case float64:
+		return strconv.FormatFloat(as, 'f', -1, 64)
+	}
+	return "unknown"
+}
+
+// str returns a string field, or "unknown" when it is missing.
+func str(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return "unknown"
+}
+
+// number returns a numeric field, or -1 when it is missing.
+func number(v interface{}) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return -1
+}
+
 // ensureEAPIEnabled attempts to SSH into the switch (agent → password → keyboard‑interactive)
 // and run the CLI commands to enable HTTP/HTTPS eAPI.
-func ensureEAPIEnabled(device inventory.Device) error {
-	// Build a list of auth methods
-	auth := []ssh.AuthMethod{ssh.Password(device.Password)}
-
-	// SSH agent support
+func ensureEAPIEnabled(ctx context.Context, device inventory.Device) error {
+	// Password and keyboard-interactive, plus the SSH agent if one is running
+	auth := collector.PasswordAuth(device)
 	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
 		if conn, err := net.Dial("unix", sock); err == nil {
+			defer conn.Close()
 			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
 		}
 	}
 
-	// Keyboard‑interactive fallback:
-	auth = append(auth, ssh.KeyboardInteractive(
-		func(user, instruction string, questions []string, echos []bool) ([]string, error) {
-			answers := make([]string, len(questions))
-			for i := range questions {
-				answers[i] = device.Password
-			}
-			return answers, nil
-		},
-	))
-
 	sshConfig := &ssh.ClientConfig{
 		User:            device.Username,
 		Auth:            auth,
@@ -169,12 +613,17 @@ func ensureEAPIEnabled(device inventory.Device) error {
 		Timeout:         5 * time.Second,
 	}
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", device.IP), sshConfig)
+	conn, err := collector.DialSSH(ctx, collector.SSHAddress(device), sshConfig)
 	if err != nil {
 		return fmt.Errorf("SSH connect failed: %w", err)
 	}
 	defer conn.Close()
 
+	// Tear the connection down if the caller gives up, which unblocks the
+	// session below.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
 	session, err := conn.NewSession()
 	if err != nil {
 		return fmt.Errorf("SSH session failed: %w", err)
@@ -195,6 +644,9 @@ func ensureEAPIEnabled(device inventory.Device) error {
 
 	// Run all commands in one go:
 	out, err := session.CombinedOutput(cmd)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to enable eAPI via CLI: %w\n%s", err, out)
 	}
@@ -203,7 +655,7 @@ func ensureEAPIEnabled(device inventory.Device) error {
 	return nil
 }
 
-func runEAPI(device inventory.Device, commands []string) ([]map[string]interface{}, error) {
+func runEAPI(ctx context.Context, device inventory.Device, commands []string) ([]map[string]interface{}, error) {
 	payload := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"method":  "runCmds",
@@ -216,31 +668,56 @@ func runEAPI(device inventory.Device, commands []string) ([]map[string]interface
 	}
 	data, _ := json.Marshal(payload)
 
-	url := fmt.Sprintf("http://%s/command-api", device.IP)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	url := collector.BaseURL(device, "http") + "/command-api"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
 	req.SetBasicAuth(device.Username, device.Password)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
-	}
-	resp, err := client.Do(req)
+	resp, err := collector.HTTPClient(device).Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	if os.Getenv("NETMETRICS_DEBUG") == "1" {
 		fmt.Printf("RAW BODY from %s:\n%s\n", device.Hostname, string(body))
 	}
 
 	var jsonResp struct {
 		Result []map[string]interface{} `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
 	}
 	if err := json.Unmarshal(body, &jsonResp); err != nil {
 		return nil, err
 	}
+	if jsonResp.Error != nil {
+		return nil, fmt.Errorf("eAPI error %d: %s", jsonResp.Error.Code, jsonResp.Error.Message)
+	}
 
 	return jsonResp.Result, nil
 }
+
+// runCmd runs a single eAPI command and returns its JSON result.
+func runCmd(ctx context.Context, device inventory.Device, command string) (map[string]interface{}, error) {
+	result, err := runEAPI(ctx, device, []string{command})
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("eAPI returned no result for %q", command)
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("DEBUG JSON RESULT from %s (%s): %+v\n", device.Hostname, command, result[0])
+	}
+	return result[0], nil
+}
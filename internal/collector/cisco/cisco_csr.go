@@ -1,26 +1,65 @@
 package cisco
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"netmetrics_exporter/internal/collector"
 	"netmetrics_exporter/internal/inventory"
 	"netmetrics_exporter/internal/metrics"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type CollectorCSR struct{}
 
-func (c CollectorCSR) Collect(device inventory.Device) error {
-	baseURL := fmt.Sprintf("https://%s/restconf/data", device.IP)
-	client := &http.Client{}
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "cisco",
+		Protocol: "restconf",
+		Capabilities: []string{
+			collector.CapInterfaces,
+			collector.CapInterfaceCounters,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapISIS,
+			collector.CapLLDP,
+			collector.CapTransceivers,
+			collector.CapCPU,
+			collector.CapMemory,
+			collector.CapEnvironment,
+		},
+		New: func() collector.Collector { return CollectorCSR{} },
+	})
+}
+
+func (c CollectorCSR) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	baseURL := collector.BaseURL(device, "https") + "/restconf/data"
 	headers := map[string]string{
 		"Accept": "application/yang-data+json",
 	}
 
+	// RESTCONF has no single reachability call, so the device only counts
+	// as failed when every section failed.
+	var lastErr error
+	succeeded := false
+	observe := func(section string, start time.Time, err error) {
+		m.ObserveSection(device.Hostname, section, start, err)
+		if err != nil {
+			lastErr = err
+		} else {
+			succeeded = true
+		}
+	}
+
 	// ===== Interface Metrics =====
+	start := time.Now()
 	intfURL := baseURL + "/ietf-interfaces:interfaces"
-	intfBody, err := restconfGet(client, intfURL, device.Username, device.Password, headers)
+	intfBody, err := restconfGet(ctx, device, intfURL, headers)
 	if err == nil {
 		var interfaceData struct {
 			Interfaces struct {
@@ -35,106 +74,563 @@ func (c CollectorCSR) Collect(device inventory.Device) error {
 				} `json:"interface"`
 			} `json:"ietf-interfaces:interfaces"`
 		}
-		if err := json.Unmarshal(intfBody, &interfaceData); err == nil {
+		if err = json.Unmarshal(intfBody, &interfaceData); err == nil {
 			for _, intf := range interfaceData.Interfaces.Interface {
 				up := 0.0
 				if intf.Enabled {
 					up = 1.0
 				}
-				metrics.InterfaceUp.WithLabelValues(device.Hostname, intf.Name, device.Vendor).Set(up)
+				m.InterfaceUp.WithLabelValues(device.Hostname, intf.Name, device.Vendor).Set(up)
+			}
+		}
+	}
+	observe("interfaces", start, err)
+
+	// ===== Interface Traffic Counters =====
+	start = time.Now()
+	statsURL := baseURL + "/ietf-interfaces:interfaces-state"
+	statsBody, err := restconfGet(ctx, device, statsURL, headers)
+	if err == nil {
+		// 64-bit counters arrive as JSON strings, which json.Number accepts.
+		var statsData struct {
+			InterfacesState struct {
+				Interface []struct {
+					Name       string `json:"name"`
+					Statistics struct {
+						InOctets     json.Number `json:"in-octets"`
+						OutOctets    json.Number `json:"out-octets"`
+						InUnicast    json.Number `json:"in-unicast-pkts"`
+						OutUnicast   json.Number `json:"out-unicast-pkts"`
+						InMulticast  json.Number `json:"in-multicast-pkts"`
+						OutMulticast json.Number `json:"out-multicast-pkts"`
+						InBroadcast  json.Number `json:"in-broadcast-pkts"`
+						OutBroadcast json.Number `json:"out-broadcast-pkts"`
+						InDiscards   json.Number `json:"in-discards"`
+						OutDiscards  json.Number `json:"out-discards"`
+					} `json:"statistics"`
+				} `json:"interface"`
+			} `json:"ietf-interfaces:interfaces-state"`
+		}
+		if err = json.Unmarshal(statsBody, &statsData); err == nil {
+			for _, intf := range statsData.InterfacesState.Interface {
+				st := intf.Statistics
+				m.SetInterfaceCounters(device.Hostname, intf.Name, metrics.InterfaceCounters{
+					InOctets:            toCounter(st.InOctets),
+					OutOctets:           toCounter(st.OutOctets),
+					InUnicastPackets:    toCounter(st.InUnicast),
+					OutUnicastPackets:   toCounter(st.OutUnicast),
+					InMulticastPackets:  toCounter(st.InMulticast),
+					OutMulticastPackets: toCounter(st.OutMulticast),
+					InBroadcastPackets:  toCounter(st.InBroadcast),
+					OutBroadcastPackets: toCounter(st.OutBroadcast),
+					InDiscards:          toCounter(st.InDiscards),
+					OutDiscards:         toCounter(st.OutDiscards),
+				})
 			}
 		}
 	}
+	observe("interface_counters", start, err)
 
-	// ===== BGP Metrics =====
-	bgpURL := baseURL + "/Cisco-IOS-XE-bgp-oper:bgp-state-data"
-	bgpBody, err := restconfGet(client, bgpURL, device.Username, device.Password, headers)
+	// ===== BGP Sessions =====
+	start = time.Now()
+	bgpURL := baseURL + "/Cisco-IOS-XE-bgp-oper:bgp-state-data/neighbors"
+	bgpBody, err := restconfGet(ctx, device, bgpURL, headers)
 	if err == nil {
+		// One entry per session and address family
 		var bgpData struct {
-			Neighbors []interface{} `json:"neighbors"`
+			Neighbors struct {
+				Neighbor []struct {
+					AFISAFI           string      `json:"afi-safi"`
+					VRF               string      `json:"vrf-name"`
+					ID                string      `json:"neighbor-id"`
+					AS                json.Number `json:"as"`
+					UpTime            string      `json:"up-time"`
+					SessionState      string      `json:"session-state"`
+					InstalledPrefixes json.Number `json:"installed-prefixes"`
+					Connection        struct {
+						State            string      `json:"state"`
+						TotalEstablished json.Number `json:"total-established"`
+					} `json:"connection"`
+					PrefixActivity struct {
+						Sent struct {
+							Current json.Number `json:"current-prefixes"`
+						} `json:"sent"`
+						Received struct {
+							Current json.Number `json:"current-prefixes"`
+						} `json:"received"`
+					} `json:"prefix-activity"`
+				} `json:"neighbor"`
+			} `json:"Cisco-IOS-XE-bgp-oper:neighbors"`
 		}
-		if err := json.Unmarshal(bgpBody, &bgpData); err == nil {
-			metrics.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(bgpData.Neighbors)))
+		if err = json.Unmarshal(bgpBody, &bgpData); err == nil {
+			peers := make(map[string]*metrics.BGPPeer)
+			var order []string
+			for _, n := range bgpData.Neighbors.Neighbor {
+				vrf := n.VRF
+				if vrf == "" {
+					vrf = "default"
+				}
+				peer, ok := peers[vrf+"|"+n.ID]
+				if !ok {
+					state := n.SessionState
+					if state == "" {
+						state = n.Connection.State
+					}
+					peer = &metrics.BGPPeer{
+						VRF:                vrf,
+						Address:            n.ID,
+						RemoteAS:           n.AS.String(),
+						State:              strings.TrimPrefix(state, "fsm-"),
+						EstablishedSeconds: parseUptime(n.UpTime),
+						Flaps:              toNumber(n.Connection.TotalEstablished),
+					}
+					peers[vrf+"|"+n.ID] = peer
+					order = append(order, vrf+"|"+n.ID)
+				}
+				peer.Families = append(peer.Families, metrics.BGPFamily{
+					AFISAFI:    n.AFISAFI,
+					Received:   toNumber(n.PrefixActivity.Received.Current),
+					Accepted:   toNumber(n.InstalledPrefixes),
+					Advertised: toNumber(n.PrefixActivity.Sent.Current),
+				})
+			}
+			for _, key := range order {
+				m.SetBGPPeer(device.Hostname, *peers[key])
+			}
+			m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
 		}
 	}
+	observe("bgp", start, err)
+
+	// ===== Optical Transceivers =====
+	start = time.Now()
+	xcvrURL := baseURL + "/Cisco-IOS-XE-transceiver-oper:transceiver-oper-data"
+	xcvrBody, err := restconfGet(ctx, device, xcvrURL, headers)
+	if err == nil {
+		var xcvrData struct {
+			Data struct {
+				Transceivers []struct {
+					Name        string      `json:"name"`
+					VendorName  string      `json:"vendor-name"`
+					VendorPart  string      `json:"vendor-part"`
+					SerialNo    string      `json:"serial-no"`
+					Temperature json.Number `json:"temperature"`
+					Voltage     json.Number `json:"voltage"`
+					Current     laneValues  `json:"current"`
+					OutputPower laneValues  `json:"output-power"`
+					InputPower  laneValues  `json:"input-power"`
+				} `json:"transceiver"`
+			} `json:"Cisco-IOS-XE-transceiver-oper:transceiver-oper-data"`
+		}
+		if err = json.Unmarshal(xcvrBody, &xcvrData); err == nil {
+			for _, x := range xcvrData.Data.Transceivers {
+				xcvr := metrics.Transceiver{
+					Interface:          x.Name,
+					Manufacturer:       strings.TrimSpace(x.VendorName),
+					PartNumber:         strings.TrimSpace(x.VendorPart),
+					Serial:             strings.TrimSpace(x.SerialNo),
+					TemperatureCelsius: reading(x.Temperature),
+					VoltageVolts:       reading(x.Voltage),
+				}
+				lanes := max(len(x.Current), len(x.OutputPower), len(x.InputPower))
+				for i := 0; i < lanes; i++ {
+					xcvr.Lanes = append(xcvr.Lanes, metrics.TransceiverLane{
+						Lane:             strconv.Itoa(i + 1),
+						RxPowerDBm:       x.InputPower.at(i),
+						TxPowerDBm:       x.OutputPower.at(i),
+						BiasMilliamperes: x.Current.at(i),
+					})
+				}
+				m.SetTransceiver(device.Hostname, xcvr)
+			}
+		}
+	}
+	observe("transceivers", start, err)
+
+	// ===== Environment Sensors =====
+	// Temperatures, fans and power supplies are all sensors; their unit
+	// tells them apart. The model carries no module state, and a CSR is a
+	// single virtual chassis, so netmetrics_module_up is not exported.
+	start = time.Now()
+	envURL := baseURL + "/Cisco-IOS-XE-environment-oper:environment-sensors"
+	envBody, err := restconfGet(ctx, device, envURL, headers)
+	if err == nil {
+		var envData struct {
+			Sensors struct {
+				Sensor []struct {
+					Name         string      `json:"name"`
+					Location     string      `json:"location"`
+					State        string      `json:"state"`
+					Reading      json.Number `json:"current-reading"`
+					Units        string      `json:"sensor-units"`
+					HighNormal   json.Number `json:"high-normal-threshold"`
+					HighCritical json.Number `json:"high-critical-threshold"`
+				} `json:"environment-sensor"`
+			} `json:"Cisco-IOS-XE-environment-oper:environment-sensors"`
+		}
+		if err = json.Unmarshal(envBody, &envData); err == nil {
+			for _, sensor := range envData.Sensors.Sensor {
+				id := sensor.Location + "/" + sensor.Name
+				value := reading(sensor.Reading)
+				units := strings.ToLower(sensor.Units)
+				name := strings.ToLower(sensor.Name)
+				switch {
+				case strings.Contains(units, "celsius"):
+					if !math.IsNaN(value) {
+						m.TemperatureCelsius.WithLabelValues(device.Hostname, id).Set(value)
+					}
+					if warn := reading(sensor.HighNormal); !math.IsNaN(warn) {
+						m.TemperatureThreshold.WithLabelValues(device.Hostname, id, "warning").Set(warn)
+					}
+					if crit := reading(sensor.HighCritical); !math.IsNaN(crit) {
+						m.TemperatureThreshold.WithLabelValues(device.Hostname, id, "critical").Set(crit)
+					}
+				case strings.Contains(units, "rpm"):
+					m.FanOK.WithLabelValues(device.Hostname, id).Set(sensorOK(sensor.State))
+					if !math.IsNaN(value) {
+						m.FanSpeedRPM.WithLabelValues(device.Hostname, id).Set(value)
+					}
+				case strings.Contains(units, "watt"):
+					// Power supplies report e.g. "P: Pin" and "P: Pout"
+					// at their location, such as "P0".
+					m.PSUOK.WithLabelValues(device.Hostname, sensor.Location).Set(sensorOK(sensor.State))
+					if math.IsNaN(value) {
+						break
+					}
+					if strings.Contains(name, "out") {
+						m.PSUOutputPowerWatts.WithLabelValues(device.Hostname, sensor.Location).Set(value)
+					} else if strings.Contains(name, "in") {
+						m.PSUInputPowerWatts.WithLabelValues(device.Hostname, sensor.Location).Set(value)
+					}
+				}
+			}
+		}
+	}
+	observe("environment", start, err)
 
 	// ===== LLDP Neighbors =====
+	start = time.Now()
 	lldpURL := baseURL + "/Cisco-IOS-XE-lldp-oper:lldp-entries"
-	lldpBody, err := restconfGet(client, lldpURL, device.Username, device.Password, headers)
+	lldpBody, err := restconfGet(ctx, device, lldpURL, headers)
 	if err == nil {
 		var lldpData struct {
 			Entries []struct {
 				LocalInterface string `json:"local-interface"`
 			} `json:"lldp-entry"`
 		}
-		if err := json.Unmarshal(lldpBody, &lldpData); err == nil {
-			metrics.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(lldpData.Entries)))
+		if err = json.Unmarshal(lldpBody, &lldpData); err == nil {
+			m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(lldpData.Entries)))
 		}
 	}
+	observe("lldp", start, err)
 
 	// ===== CPU Usage =====
+	start = time.Now()
 	cpuURL := baseURL + "/Cisco-IOS-XE-process-cpu-oper:cpu-usage"
-	cpuBody, err := restconfGet(client, cpuURL, device.Username, device.Password, headers)
+	cpuBody, err := restconfGet(ctx, device, cpuURL, headers)
 	if err == nil {
 		var cpuData struct {
-			FiveSec int `json:"five-seconds"`
+			Usage struct {
+				Utilization struct {
+					FiveSeconds json.Number `json:"five-seconds"`
+					OneMinute   json.Number `json:"one-minute"`
+					FiveMinutes json.Number `json:"five-minutes"`
+				} `json:"cpu-utilization"`
+			} `json:"Cisco-IOS-XE-process-cpu-oper:cpu-usage"`
 		}
-		if err := json.Unmarshal(cpuBody, &cpuData); err == nil {
-			metrics.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(float64(cpuData.FiveSec))
+		if err = json.Unmarshal(cpuBody, &cpuData); err == nil {
+			cpu := cpuData.Usage.Utilization
+			if v := reading(cpu.FiveSeconds); !math.IsNaN(v) {
+				m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(v)
+			}
+			if v := reading(cpu.OneMinute); !math.IsNaN(v) {
+				m.CPUUsageAverage.WithLabelValues(device.Hostname, device.Vendor, "1m").Set(v)
+			}
+			if v := reading(cpu.FiveMinutes); !math.IsNaN(v) {
+				m.CPUUsageAverage.WithLabelValues(device.Hostname, device.Vendor, "5m").Set(v)
+			}
 		}
 	}
+	observe("cpu", start, err)
 
 	// ===== Memory Usage =====
+	start = time.Now()
 	memURL := baseURL + "/Cisco-IOS-XE-memory-oper:memory-statistics"
-	memBody, err := restconfGet(client, memURL, device.Username, device.Password, headers)
+	memBody, err := restconfGet(ctx, device, memURL, headers)
 	if err == nil {
+		// The first pool is the processor's; sizes are in bytes
 		var memData struct {
-			MemoryStats []struct {
-				Used  int `json:"used-memory"`
-				Total int `json:"total-memory"`
-			} `json:"memory-statistic"`
+			Statistics struct {
+				MemoryStats []struct {
+					Used  json.Number `json:"used-memory"`
+					Total json.Number `json:"total-memory"`
+				} `json:"memory-statistic"`
+			} `json:"Cisco-IOS-XE-memory-oper:memory-statistics"`
 		}
-		if err := json.Unmarshal(memBody, &memData); err == nil && len(memData.MemoryStats) > 0 {
-			usedPct := float64(memData.MemoryStats[0].Used) / float64(memData.MemoryStats[0].Total) * 100
-			metrics.MemoryUsage.WithLabelValues(device.Hostname, device.Vendor).Set(usedPct)
+		if err = json.Unmarshal(memBody, &memData); err == nil && len(memData.Statistics.MemoryStats) > 0 {
+			pool := memData.Statistics.MemoryStats[0]
+			m.SetMemory(device.Hostname, device.Vendor, toCounter(pool.Total), toCounter(pool.Used))
 		}
 	}
-	// ===== OSPF Neighbors =====
+	observe("memory", start, err)
+
+	// ===== OSPF Adjacencies =====
+	start = time.Now()
 	ospfURL := baseURL + "/Cisco-IOS-XE-ospf-oper:ospf-oper-data"
-	ospfBody, err := restconfGet(client, ospfURL, device.Username, device.Password, headers)
+	ospfBody, err := restconfGet(ctx, device, ospfURL, headers)
 	if err == nil {
+		// 16.x fills ospf-state; 17.x adds ospfv2-instance, which also
+		// carries the VRF. Neither has the adjacency uptime, so
+		// netmetrics_ospf_neighbor_uptime_seconds stays unset (see the
+		// README).
 		var ospfData struct {
-			Ospfv2 []struct {
-				Ospfv2Neighbor []interface{} `json:"ospfv2-neighbor"`
-			} `json:"ospf-state/neighbors"`
-		}
-		if err := json.Unmarshal(ospfBody, &ospfData); err == nil {
-			count := 0
-			for _, ospf := range ospfData.Ospfv2 {
-				count += len(ospf.Ospfv2Neighbor)
+			Data struct {
+				State struct {
+					Instances []struct {
+						ProcessID json.Number `json:"process-id"`
+						Areas     []struct {
+							AreaID     json.Number `json:"area-id"`
+							Interfaces []struct {
+								Name      string `json:"name"`
+								Neighbors []struct {
+									ID      string `json:"neighbor-id"`
+									Address string `json:"address"`
+									State   string `json:"state"`
+								} `json:"ospf-neighbor"`
+							} `json:"ospf-interface"`
+						} `json:"ospf-area"`
+					} `json:"ospf-instance"`
+				} `json:"ospf-state"`
+				V2Instances []struct {
+					InstanceID json.Number `json:"instance-id"`
+					VRF        string      `json:"vrf-name"`
+					Areas      []struct {
+						AreaID     json.Number `json:"area-id"`
+						Interfaces []struct {
+							Name      string `json:"name"`
+							Neighbors []struct {
+								ID      string `json:"nbr-id"`
+								Address string `json:"address"`
+								State   string `json:"state"`
+							} `json:"ospfv2-neighbor"`
+						} `json:"ospfv2-interface"`
+					} `json:"ospfv2-area"`
+				} `json:"ospfv2-instance"`
+			} `json:"Cisco-IOS-XE-ospf-oper:ospf-oper-data"`
+		}
+		if err = json.Unmarshal(ospfBody, &ospfData); err == nil {
+			var neighbors []metrics.OSPFNeighbor
+			for _, inst := range ospfData.Data.V2Instances {
+				vrf := inst.VRF
+				if vrf == "" {
+					vrf = "default"
+				}
+				for _, area := range inst.Areas {
+					for _, intf := range area.Interfaces {
+						for _, nbr := range intf.Neighbors {
+							neighbors = append(neighbors, metrics.OSPFNeighbor{
+								VRF:           vrf,
+								Instance:      inst.InstanceID.String(),
+								Area:          dottedArea(area.AreaID),
+								Interface:     intf.Name,
+								RouterID:      nbr.ID,
+								Address:       nbr.Address,
+								State:         nbr.State,
+								UptimeSeconds: -1,
+							})
+						}
+					}
+				}
+			}
+			if len(ospfData.Data.V2Instances) == 0 {
+				for _, inst := range ospfData.Data.State.Instances {
+					for _, area := range inst.Areas {
+						for _, intf := range area.Interfaces {
+							for _, nbr := range intf.Neighbors {
+								neighbors = append(neighbors, metrics.OSPFNeighbor{
+									VRF:           "default",
+									Instance:      inst.ProcessID.String(),
+									Area:          dottedArea(area.AreaID),
+									Interface:     intf.Name,
+									RouterID:      nbr.ID,
+									Address:       nbr.Address,
+									State:         nbr.State,
+									UptimeSeconds: -1,
+								})
+							}
+						}
+					}
+				}
+			}
+			for _, n := range neighbors {
+				m.SetOSPFNeighbor(device.Hostname, n)
+			}
+			m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(neighbors)))
+		}
+	}
+	observe("ospf", start, err)
+
+	// ===== IS-IS Adjacencies =====
+	// The model reports neither adjacency uptime nor the LSP database, so
+	// netmetrics_isis_lsp_database_lsps stays unset (see the README).
+	start = time.Now()
+	isisURL := baseURL + "/Cisco-IOS-XE-isis-oper:isis-oper-data"
+	isisBody, err := restconfGet(ctx, device, isisURL, headers)
+	if err == nil {
+		var isisData struct {
+			Data struct {
+				Instances []struct {
+					Tag       string `json:"tag"`
+					Neighbors []struct {
+						SystemID string      `json:"system-id"`
+						Level    string      `json:"level"`
+						IfName   string      `json:"if-name"`
+						State    string      `json:"state"`
+						HoldTime json.Number `json:"holdtime"`
+					} `json:"isis-neighbor"`
+				} `json:"isis-instance"`
+			} `json:"Cisco-IOS-XE-isis-oper:isis-oper-data"`
+		}
+		if err = json.Unmarshal(isisBody, &isisData); err == nil {
+			adjacencies := 0
+			for _, inst := range isisData.Data.Instances {
+				for _, nbr := range inst.Neighbors {
+					m.SetISISAdjacency(device.Hostname, metrics.ISISAdjacency{
+						Instance:        inst.Tag,
+						SystemID:        nbr.SystemID,
+						Interface:       nbr.IfName,
+						Level:           nbr.Level,
+						State:           nbr.State,
+						HoldTimeSeconds: toNumber(nbr.HoldTime),
+						UptimeSeconds:   -1,
+					})
+					adjacencies++
+				}
 			}
-			metrics.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(count))
+			m.ISISAdjacencies.WithLabelValues(device.Hostname, device.Vendor).Set(float64(adjacencies))
 		}
 	}
+	observe("isis", start, err)
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !succeeded {
+		return lastErr
+	}
 	return nil
 }
 
-func restconfGet(client *http.Client, url, username, password string, headers map[string]string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// toCounter reads a counter leaf; absent or malformed leaves read as 0.
+func toCounter(n json.Number) float64 {
+	f, err := n.Float64()
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// toNumber reads a numeric leaf, or returns -1 when it is absent.
+func toNumber(n json.Number) float64 {
+	f, err := n.Float64()
+	if err != nil {
+		return -1
+	}
+	return f
+}
+
+// sensorOK maps the state of an environment sensor to 1 when it is healthy.
+func sensorOK(state string) float64 {
+	switch strings.ToLower(state) {
+	case "normal", "good", "ok":
+		return 1
+	}
+	return 0
+}
+
+// reading reads a sensor leaf, or returns NaN when it is absent.
+func reading(n json.Number) float64 {
+	f, err := n.Float64()
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// laneValues decodes a per-lane reading, sent as a list on multi-lane
+// optics and as a single value otherwise.
+type laneValues []json.Number
+
+func (l *laneValues) UnmarshalJSON(data []byte) error {
+	var list []json.Number
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+	var one json.Number
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	*l = laneValues{one}
+	return nil
+}
+
+// at returns the reading of lane i, counted from 0, or NaN.
+func (l laneValues) at(i int) float64 {
+	if i >= len(l) {
+		return math.NaN()
+	}
+	return reading(l[i])
+}
+
+// dottedArea formats a numeric OSPF area ID the way other vendors print it,
+// e.g. 0 as "0.0.0.0".
+func dottedArea(id json.Number) string {
+	n, err := strconv.ParseUint(id.String(), 10, 32)
+	if err != nil {
+		return id.String()
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", n>>24, n>>16&0xff, n>>8&0xff, n&0xff)
+}
+
+// parseUptime reads IOS durations such as "00:12:34", "3d04h" or "2w1d"
+// into seconds, or returns -1 for "never" and anything else it cannot read.
+func parseUptime(s string) float64 {
+	var h, m, sec int
+	if _, err := fmt.Sscanf(s, "%d:%d:%d", &h, &m, &sec); err == nil {
+		return float64(h*3600 + m*60 + sec)
+	}
+	units := map[byte]float64{'y': 365 * 86400, 'w': 7 * 86400, 'd': 86400, 'h': 3600, 'm': 60, 's': 1}
+	total, n, seen := 0.0, 0, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+		case units[c] > 0:
+			total += float64(n) * units[c]
+			n, seen = 0, true
+		default:
+			return -1
+		}
+	}
+	if !seen || n != 0 {
+		return -1
+	}
+	return total
+}
+
+func restconfGet(ctx context.Context, device inventory.Device, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(username, password)
+	req.SetBasicAuth(device.Username, device.Password)
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.TLSClientConfig.InsecureSkipVerify = true
-	client.Transport = transport
-
-	resp, err := client.Do(req)
+	resp, err := collector.HTTPClient(device).Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("RESTCONF error: %v", err)
 	}
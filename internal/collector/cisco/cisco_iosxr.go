@@ -0,0 +1,435 @@
+package cisco
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/collector/netconf"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// CollectorIOSXR polls IOS-XR routers over NETCONF with the native
+// Cisco-IOS-XR-*-oper models. "netconf-yang agent ssh" must be configured.
+type CollectorIOSXR struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "iosxr",
+		Protocol: "netconf",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapISIS,
+			collector.CapLLDP,
+			collector.CapCPU,
+			collector.CapMemory,
+		},
+		New: func() collector.Collector { return CollectorIOSXR{} },
+	})
+}
+
+// Subtree filters, one per section.
+const (
+	xrUptimeFilter    = `<system-time xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-shellutil-oper"><uptime/></system-time>`
+	xrInventoryFilter = `<inventory xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-invmgr-oper"><racks><rack><attributes><inv-basic-bag/></attributes></rack></racks></inventory>`
+	xrInterfaceFilter = `<interfaces xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-pfi-im-cmd-oper"><interface-xr><interface/></interface-xr></interfaces>`
+	xrBGPFilter       = `<bgp xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-ipv4-bgp-oper"><instances><instance><instance-active>` +
+		`<default-vrf><neighbors/></default-vrf><vrfs><vrf><vrf-name/><neighbors/></vrf></vrfs>` +
+		`</instance-active></instance></instances></bgp>`
+	xrOSPFFilter = `<ospf xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-ipv4-ospf-oper"><processes><process><process-name/>` +
+		`<default-vrf><adjacency-information><neighbor-details/></adjacency-information></default-vrf>` +
+		`<vrfs><vrf><vrf-name/><adjacency-information><neighbor-details/></adjacency-information></vrf></vrfs>` +
+		`</process></processes></ospf>`
+	xrISISFilter   = `<isis xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-clns-isis-oper"><instances><instance><instance-name/><neighbors/></instance></instances></isis>`
+	xrLLDPFilter   = `<lldp xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-ethernet-lldp-oper"><nodes><node><neighbors><summaries/></neighbors></node></nodes></lldp>`
+	xrCPUFilter    = `<system-monitoring xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-wdsysmon-fd-oper"><cpu-utilization/></system-monitoring>`
+	xrMemoryFilter = `<memory-summary xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-nto-misc-oper"><nodes><node><summary/></node></nodes></memory-summary>`
+)
+
+// Every struct below decodes the <data> element of a <get> reply.
+
+type xrUptime struct {
+	Uptime float64 `xml:"system-time>uptime>uptime"`
+}
+
+type xrInventory struct {
+	Racks []struct {
+		Model    string `xml:"attributes>inv-basic-bag>model-name"`
+		Software string `xml:"attributes>inv-basic-bag>software-revision"`
+	} `xml:"inventory>racks>rack"`
+}
+
+type xrInterfaces struct {
+	Interfaces []struct {
+		Name         string  `xml:"interface-name"`
+		LineState    string  `xml:"line-state"`
+		Bandwidth    float64 `xml:"bandwidth"` // kbps
+		Duplexity    string  `xml:"duplexity"`
+		InputErrors  float64 `xml:"interface-statistics>full-interface-stats>input-errors"`
+		OutputErrors float64 `xml:"interface-statistics>full-interface-stats>output-errors"`
+	} `xml:"interfaces>interface-xr>interface"`
+}
+
+type xrBGP struct {
+	Instances []struct {
+		Default []xrBGPNeighbor `xml:"instance-active>default-vrf>neighbors>neighbor"`
+		VRFs    []struct {
+			Name      string          `xml:"vrf-name"`
+			Neighbors []xrBGPNeighbor `xml:"neighbors>neighbor"`
+		} `xml:"instance-active>vrfs>vrf"`
+	} `xml:"bgp>instances>instance"`
+}
+
+// Numeric leaves that a release may leave out are read as strings and
+// parsed with xrNumber.
+type xrBGPNeighbor struct {
+	Address         string `xml:"neighbor-address"`
+	RemoteAS        string `xml:"remote-as"`
+	State           string `xml:"connection-state"` // bgp-st-estab, ...
+	EstablishedTime string `xml:"connection-established-time"`
+	DownCount       string `xml:"connection-down-count"`
+	Families        []struct {
+		Name       string `xml:"af-name"`
+		Accepted   string `xml:"prefixes-accepted"`
+		Denied     string `xml:"prefixes-denied"`
+		Advertised string `xml:"prefixes-advertised"`
+	} `xml:"af-data"`
+}
+
+type xrOSPF struct {
+	Processes []struct {
+		Name    string           `xml:"process-name"`
+		Default []xrOSPFNeighbor `xml:"default-vrf>adjacency-information>neighbor-details>neighbor-detail"`
+		VRFs    []struct {
+			Name      string           `xml:"vrf-name"`
+			Neighbors []xrOSPFNeighbor `xml:"adjacency-information>neighbor-details>neighbor-detail"`
+		} `xml:"vrfs>vrf"`
+	} `xml:"ospf>processes>process"`
+}
+
+type xrOSPFNeighbor struct {
+	RouterID  string `xml:"neighbor-summary>neighbor-id"`
+	Address   string `xml:"neighbor-summary>neighbor-ip-address"`
+	Interface string `xml:"neighbor-summary>neighbor-interface-name"`
+	State     string `xml:"neighbor-summary>neighbor-state"` // mgmt-nbr-full, ...
+	Area      string `xml:"neighbor-area-id"`
+	UpTime    string `xml:"neighbor-up-time"`
+}
+
+type xrISIS struct {
+	Instances []struct {
+		Name      string `xml:"instance-name"`
+		Neighbors []struct {
+			SystemID  string `xml:"system-id"`
+			Interface string `xml:"interface-name"`
+			State     string `xml:"neighbor-state"`        // isis-adj-up-state, ...
+			Level     string `xml:"neighbor-circuit-type"` // isis-levels-12, ...
+			HoldTime  string `xml:"neighbor-holdtime"`
+			Uptime    string `xml:"neighbor-uptime"`
+		} `xml:"neighbors>neighbor"`
+	} `xml:"isis>instances>instance"`
+}
+
+type xrLLDP struct {
+	Nodes []struct {
+		Summaries []struct{} `xml:"neighbors>summaries>summary"`
+	} `xml:"lldp>nodes>node"`
+}
+
+type xrCPU struct {
+	Nodes []struct {
+		Name       string  `xml:"node-name"`
+		OneMinute  float64 `xml:"total-cpu-one-minute"`
+		FiveMinute float64 `xml:"total-cpu-five-minute"`
+	} `xml:"system-monitoring>cpu-utilization"`
+}
+
+type xrMemory struct {
+	Nodes []struct {
+		Name     string  `xml:"node-name"`
+		Physical float64 `xml:"summary>physical-memory"`
+		Free     float64 `xml:"summary>free-physical-memory"`
+	} `xml:"memory-summary>nodes>node"`
+}
+
+func (c CollectorIOSXR) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// One NETCONF session carries every request. Opening it and reading
+	// the uptime double as the reachability check.
+	start := time.Now()
+	session, err := netconf.Dial(ctx, device)
+	if err != nil {
+		m.ObserveSection(device.Hostname, "system", start, err)
+		return err
+	}
+	defer session.Close()
+
+	// 1) Uptime, model & version
+	var uptime xrUptime
+	err = xrGet(ctx, session, device, xrUptimeFilter, &uptime)
+	if err != nil {
+		m.ObserveSection(device.Hostname, "system", start, err)
+		return err
+	}
+	m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime.Uptime)
+
+	var inv xrInventory
+	err = xrGet(ctx, session, device, xrInventoryFilter, &inv)
+	if err == nil && len(inv.Racks) > 0 {
+		rack := inv.Racks[0]
+		m.DeviceInfo.WithLabelValues(device.Hostname, strings.TrimSpace(rack.Model), strings.TrimSpace(rack.Software)).Set(1)
+	}
+	m.ObserveSection(device.Hostname, "system", start, err)
+
+	// 2) Interfaces and their error counters
+	start = time.Now()
+	var ifaces xrInterfaces
+	err = xrGet(ctx, session, device, xrInterfaceFilter, &ifaces)
+	if err == nil {
+		for _, iface := range ifaces.Interfaces {
+			up := 0.0
+			if iface.LineState == "im-state-up" {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, iface.Name, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, iface.Name, device.Vendor).Set(iface.Bandwidth / 1000)
+
+			duplex := strings.TrimPrefix(iface.Duplexity, "im-attr-duplex-")
+			if duplex == "" {
+				duplex = "unknown"
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, iface.Name, device.Vendor, duplex).Set(1)
+
+			m.InterfaceInputErrors.WithLabelValues(device.Hostname, iface.Name).Set(iface.InputErrors)
+			m.InterfaceOutputErrors.WithLabelValues(device.Hostname, iface.Name).Set(iface.OutputErrors)
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	// 3) BGP sessions per VRF and address family, in the default VRF and
+	// every other VRF
+	start = time.Now()
+	var bgp xrBGP
+	err = xrGet(ctx, session, device, xrBGPFilter, &bgp)
+	if err == nil {
+		peers := make(map[string]bool)
+		setPeer := func(vrf string, n xrBGPNeighbor) {
+			peers[vrf+"|"+n.Address] = true
+			m.SetBGPPeer(device.Hostname, xrBGPPeer(vrf, n))
+		}
+		for _, inst := range bgp.Instances {
+			for _, n := range inst.Default {
+				setPeer("default", n)
+			}
+			for _, vrf := range inst.VRFs {
+				for _, n := range vrf.Neighbors {
+					setPeer(vrf.Name, n)
+				}
+			}
+		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
+	}
+	m.ObserveSection(device.Hostname, "bgp", start, err)
+
+	// 4) OSPF adjacencies in every process and VRF
+	start = time.Now()
+	var ospf xrOSPF
+	err = xrGet(ctx, session, device, xrOSPFFilter, &ospf)
+	if err == nil {
+		neighbors := 0
+		setNeighbor := func(process, vrf string, n xrOSPFNeighbor) {
+			neighbors++
+			m.SetOSPFNeighbor(device.Hostname, metrics.OSPFNeighbor{
+				VRF:           vrf,
+				Instance:      process,
+				Area:          n.Area,
+				Interface:     n.Interface,
+				RouterID:      n.RouterID,
+				Address:       n.Address,
+				State:         strings.TrimPrefix(n.State, "mgmt-nbr-"),
+				UptimeSeconds: xrNumber(n.UpTime),
+			})
+		}
+		for _, proc := range ospf.Processes {
+			for _, n := range proc.Default {
+				setNeighbor(proc.Name, "default", n)
+			}
+			for _, vrf := range proc.VRFs {
+				for _, n := range vrf.Neighbors {
+					setNeighbor(proc.Name, vrf.Name, n)
+				}
+			}
+		}
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+	}
+	m.ObserveSection(device.Hostname, "ospf", start, err)
+
+	// 5) IS-IS adjacencies in every instance
+	start = time.Now()
+	var isis xrISIS
+	err = xrGet(ctx, session, device, xrISISFilter, &isis)
+	if err == nil {
+		adjacencies := 0
+		for _, inst := range isis.Instances {
+			adjacencies += len(inst.Neighbors)
+			for _, n := range inst.Neighbors {
+				m.SetISISAdjacency(device.Hostname, metrics.ISISAdjacency{
+					Instance:        inst.Name,
+					SystemID:        n.SystemID,
+					Interface:       n.Interface,
+					Level:           n.Level,
+					State:           strings.TrimSuffix(n.State, "-state"),
+					HoldTimeSeconds: xrNumber(n.HoldTime),
+					UptimeSeconds:   xrNumber(n.Uptime),
+				})
+			}
+		}
+		m.ISISAdjacencies.WithLabelValues(device.Hostname, device.Vendor).Set(float64(adjacencies))
+	}
+	m.ObserveSection(device.Hostname, "isis", start, err)
+
+	// 6) LLDP neighbors on every line card
+	start = time.Now()
+	var lldp xrLLDP
+	err = xrGet(ctx, session, device, xrLLDPFilter, &lldp)
+	if err == nil {
+		neighbors := 0
+		for _, node := range lldp.Nodes {
+			neighbors += len(node.Summaries)
+		}
+		m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	// 7) CPU of the active route processor
+	start = time.Now()
+	var cpu xrCPU
+	err = xrGet(ctx, session, device, xrCPUFilter, &cpu)
+	if err == nil {
+		names := make([]string, len(cpu.Nodes))
+		for i, n := range cpu.Nodes {
+			names[i] = n.Name
+		}
+		if i := xrRouteProcessor(names); i >= 0 {
+			m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(cpu.Nodes[i].OneMinute)
+			m.CPUUsageAverage.WithLabelValues(device.Hostname, device.Vendor, "1m").Set(cpu.Nodes[i].OneMinute)
+			m.CPUUsageAverage.WithLabelValues(device.Hostname, device.Vendor, "5m").Set(cpu.Nodes[i].FiveMinute)
+		}
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+
+	// 8) Memory of the active route processor (reported in bytes)
+	start = time.Now()
+	var mem xrMemory
+	err = xrGet(ctx, session, device, xrMemoryFilter, &mem)
+	if err == nil {
+		names := make([]string, len(mem.Nodes))
+		for i, n := range mem.Nodes {
+			names[i] = n.Name
+		}
+		if i := xrRouteProcessor(names); i >= 0 {
+			node := mem.Nodes[i]
+			m.SetMemory(device.Hostname, device.Vendor, node.Physical, node.Physical-node.Free)
+		}
+	}
+	m.ObserveSection(device.Hostname, "memory", start, err)
+
+	return ctx.Err()
+}
+
+// xrGet runs one subtree <get> and, with NETMETRICS_DEBUG=1, dumps the
+// decoded reply.
+func xrGet(ctx context.Context, session *netconf.Session, device inventory.Device, filter string, v interface{}) error {
+	if err := session.Get(ctx, filter, v); err != nil {
+		return err
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("DEBUG NETCONF RESULT from %s: %+v\n", device.Hostname, v)
+	}
+	return nil
+}
+
+// xrBGPPeer converts one neighbor of the BGP oper model.
+func xrBGPPeer(vrf string, n xrBGPNeighbor) metrics.BGPPeer {
+	peer := metrics.BGPPeer{
+		VRF:                vrf,
+		Address:            n.Address,
+		RemoteAS:           n.RemoteAS,
+		State:              xrBGPState(n.State),
+		EstablishedSeconds: xrNumber(n.EstablishedTime),
+		Flaps:              xrNumber(n.DownCount),
+	}
+	for _, f := range n.Families {
+		accepted, denied := xrNumber(f.Accepted), xrNumber(f.Denied)
+		received := -1.0
+		if accepted >= 0 && denied >= 0 {
+			received = accepted + denied
+		}
+		peer.Families = append(peer.Families, metrics.BGPFamily{
+			AFISAFI:    xrAFISAFI(f.Name),
+			Received:   received,
+			Accepted:   accepted,
+			Advertised: xrNumber(f.Advertised),
+		})
+	}
+	return peer
+}
+
+// xrBGPState turns connection states such as "bgp-st-estab" into FSM
+// state names.
+func xrBGPState(state string) string {
+	state = strings.TrimPrefix(state, "bgp-st-")
+	if state == "estab" {
+		return "established"
+	}
+	return state
+}
+
+// xrAFISAFI spells the bgp-afi names of the oper model like the other
+// collectors do; names without a common spelling are kept.
+func xrAFISAFI(afi string) string {
+	switch afi {
+	case "ipv4", "ipv6":
+		return afi + "-unicast"
+	case "vpnv4":
+		return "l3vpn-ipv4-unicast"
+	case "vpnv6":
+		return "l3vpn-ipv6-unicast"
+	case "l2vpn-evpn":
+		return "evpn"
+	}
+	return afi
+}
+
+// xrNumber reads an optional numeric leaf, or returns -1 when it is absent.
+func xrNumber(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// xrRouteProcessor picks the node that speaks for the chassis: the first
+// route processor (node names such as 0/RP0/CPU0 or 0/RSP0/CPU0), or the
+// only node of a fixed system. It returns -1 if there is none.
+func xrRouteProcessor(nodes []string) int {
+	for i, name := range nodes {
+		if strings.Contains(name, "/RP") || strings.Contains(name, "/RSP") {
+			return i
+		}
+	}
+	if len(nodes) > 0 {
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,358 @@
+package cisco
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// CollectorNXOS polls Nexus switches through NX-API. Every command is a
+// JSON-RPC "cli" call, the JSON-RPC form of a cli_show request, so the
+// device returns structured output. "feature nxapi" must be enabled.
+type CollectorNXOS struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "nxos",
+		Protocol: "nxapi",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapLLDP,
+			collector.CapCPU,
+			collector.CapMemory,
+			collector.CapEnvironment,
+		},
+		New: func() collector.Collector { return CollectorNXOS{} },
+	})
+}
+
+func (c CollectorNXOS) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// "show version" doubles as the reachability check, every other
+	// section only costs itself when it fails.
+
+	// 1) Version & uptime
+	start := time.Now()
+	ver, err := nxapiRun(ctx, device, "show version")
+	m.ObserveSection(device.Hostname, "system", start, err)
+	if err != nil {
+		return err
+	}
+	version := nxString(ver["nxos_ver_str"])
+	if version == "" {
+		version = nxString(ver["sys_ver_str"])
+	}
+	if version == "" {
+		version = nxString(ver["kickstart_ver_str"])
+	}
+	m.DeviceInfo.WithLabelValues(device.Hostname, nxString(ver["chassis_id"]), version).Set(1)
+	days, _ := nxNumber(ver["kern_uptm_days"])
+	hrs, _ := nxNumber(ver["kern_uptm_hrs"])
+	mins, _ := nxNumber(ver["kern_uptm_mins"])
+	secs, _ := nxNumber(ver["kern_uptm_secs"])
+	m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(days*86400 + hrs*3600 + mins*60 + secs)
+
+	// 2) Interfaces
+	start = time.Now()
+	ifResp, err := nxapiRun(ctx, device, "show interface")
+	if err == nil {
+		for _, row := range nxRows(ifResp, "interface") {
+			name := nxString(row["interface"])
+			if name == "" {
+				continue
+			}
+
+			state := nxString(row["state"])
+			if state == "" {
+				state = nxString(row["svi_line_proto"])
+			}
+			up := 0.0
+			if state == "up" {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(nxSpeedMbps(nxString(row["eth_speed"])))
+
+			duplex := "unknown"
+			if d := nxString(row["eth_duplex"]); d != "" {
+				duplex = d
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+
+	// 3) Interface error counters
+	start = time.Now()
+	errResp, err := nxapiRun(ctx, device, "show interface counters errors")
+	if err == nil {
+		for _, row := range nxRows(errResp, "interface") {
+			name := nxString(row["interface"])
+			if in, ok := nxNumber(row["eth_rcv_err"]); ok {
+				m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(in)
+			}
+			if out, ok := nxNumber(row["eth_xmit_err"]); ok {
+				m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(out)
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	// 4) BGP peers in every VRF. A peer is listed once per address family
+	// it carries, so peers are counted by VRF and address.
+	start = time.Now()
+	bgpResp, err := nxapiRun(ctx, device, "show ip bgp summary vrf all")
+	if err == nil {
+		peers := make(map[[2]string]bool)
+		for _, vrf := range nxRows(bgpResp, "vrf") {
+			vrfName := nxString(vrf["vrf-name-out"])
+			for _, af := range nxRows(vrf, "af") {
+				for _, saf := range nxRows(af, "saf") {
+					for _, neighbor := range nxRows(saf, "neighbor") {
+						peers[[2]string{vrfName, nxString(neighbor["neighborid"])}] = true
+					}
+				}
+			}
+		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
+	}
+	m.ObserveSection(device.Hostname, "bgp", start, err)
+
+	// 5) OSPF neighbors in every VRF
+	start = time.Now()
+	ospfResp, err := nxapiRun(ctx, device, "show ip ospf neighbors vrf all")
+	if err == nil {
+		neighbors := 0
+		for _, ctxRow := range nxRows(ospfResp, "ctx") {
+			neighbors += len(nxRows(ctxRow, "nbr"))
+		}
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+	}
+	m.ObserveSection(device.Hostname, "ospf", start, err)
+
+	// 6) LLDP neighbors
+	start = time.Now()
+	lldpResp, err := nxapiRun(ctx, device, "show lldp neighbors")
+	if err == nil {
+		m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(nxRows(lldpResp, "nbor"))))
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	// 7) System resources: CPU and memory (reported in KB)
+	start = time.Now()
+	res, err := nxapiRun(ctx, device, "show system resources")
+	if err == nil {
+		if idle, ok := nxNumber(res["cpu_state_idle"]); ok {
+			m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(100 - idle)
+		}
+		for window, key := range map[string]string{"1m": "load_avg_1min", "5m": "load_avg_5min", "15m": "load_avg_15min"} {
+			if load, ok := nxNumber(res[key]); ok {
+				m.CPULoadAverage.WithLabelValues(device.Hostname, device.Vendor, window).Set(load)
+			}
+		}
+		total, okTotal := nxNumber(res["memory_usage_total"])
+		used, okUsed := nxNumber(res["memory_usage_used"])
+		if okTotal && okUsed {
+			m.SetMemory(device.Hostname, device.Vendor, total*1024, used*1024)
+		}
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+	m.ObserveSection(device.Hostname, "memory", start, err)
+
+	// 8) Environment: temperatures, fans and power supplies
+	start = time.Now()
+	env, err := nxapiRun(ctx, device, "show environment")
+	if err == nil {
+		for _, row := range nxRows(env, "tempinfo") {
+			sensor := nxString(row["tempmod"]) + "/" + nxString(row["sensor"])
+			if cur, ok := nxNumber(row["curtemp"]); ok {
+				m.TemperatureCelsius.WithLabelValues(device.Hostname, sensor).Set(cur)
+			}
+			if minor, ok := nxNumber(row["minthres"]); ok {
+				m.TemperatureThreshold.WithLabelValues(device.Hostname, sensor, "warning").Set(minor)
+			}
+			if major, ok := nxNumber(row["majthres"]); ok {
+				m.TemperatureThreshold.WithLabelValues(device.Hostname, sensor, "critical").Set(major)
+			}
+		}
+		fans, _ := env["fandetails"].(map[string]interface{})
+		for _, row := range nxRows(fans, "faninfo") {
+			if status := nxString(row["fanstatus"]); status != "" && !strings.EqualFold(status, "absent") {
+				m.FanOK.WithLabelValues(device.Hostname, nxString(row["fanname"])).Set(nxOK(status))
+			}
+		}
+		psus, _ := env["powersup"].(map[string]interface{})
+		for _, row := range nxRows(psus, "psinfo") {
+			if status := nxString(row["ps_status"]); status != "" && !strings.EqualFold(status, "absent") {
+				m.PSUOK.WithLabelValues(device.Hostname, nxString(row["psnum"])).Set(nxOK(status))
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "environment", start, err)
+
+	return ctx.Err()
+}
+
+// nxapiRun runs one show command over NX-API JSON-RPC and returns its body.
+// Commands with no output (no neighbors, feature without state) return an
+// empty body rather than an error.
+func nxapiRun(ctx context.Context, device inventory.Device, command string) (map[string]interface{}, error) {
+	payload := []map[string]interface{}{{
+		"jsonrpc": "2.0",
+		"method":  "cli",
+		"params": map[string]interface{}{
+			"cmd":     command,
+			"version": 1,
+		},
+		"id": 1,
+	}}
+	data, _ := json.Marshal(payload)
+
+	url := collector.BaseURL(device, "https") + "/ins"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(device.Username, device.Password)
+	req.Header.Set("Content-Type", "application/json-rpc")
+
+	resp, err := collector.HTTPClient(device).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("NX-API error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("RAW NX-API BODY from %s (%s):\n%s\n", device.Hostname, command, string(body))
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("NX-API error: %s", resp.Status)
+	}
+
+	// A single request is answered with a single object, a batch with a list.
+	type rpcResponse struct {
+		Result *struct {
+			Body map[string]interface{} `json:"body"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				Msg string `json:"msg"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	var jsonResp rpcResponse
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var list []rpcResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("NX-API returned no result for %q", command)
+		}
+		jsonResp = list[0]
+	} else if err := json.Unmarshal(body, &jsonResp); err != nil {
+		return nil, err
+	}
+
+	if jsonResp.Error != nil {
+		msg := strings.TrimSpace(jsonResp.Error.Data.Msg)
+		if msg == "" {
+			msg = jsonResp.Error.Message
+		}
+		return nil, fmt.Errorf("NX-API error %d for %q: %s", jsonResp.Error.Code, command, msg)
+	}
+	if jsonResp.Result == nil || jsonResp.Result.Body == nil {
+		return map[string]interface{}{}, nil
+	}
+	return jsonResp.Result.Body, nil
+}
+
+// nxRows unwraps NX-OS's TABLE_<name>/ROW_<name> nesting. ROW_<name> is an
+// object when there is a single row and a list otherwise.
+func nxRows(parent map[string]interface{}, name string) []map[string]interface{} {
+	table, _ := parent["TABLE_"+name].(map[string]interface{})
+	switch rows := table["ROW_"+name].(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{rows}
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(rows))
+		for _, r := range rows {
+			if row, ok := r.(map[string]interface{}); ok {
+				out = append(out, row)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func nxString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+// nxNumber reads a number that NX-OS may send either as a JSON number or
+// as a string.
+func nxNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// nxSpeedMbps parses eth_speed values such as "10 Gb/s" or "1000 Mb/s".
+func nxSpeedMbps(speed string) float64 {
+	fields := strings.Fields(speed)
+	if len(fields) != 2 {
+		return -1
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1
+	}
+	switch strings.ToLower(fields[1]) {
+	case "gb/s":
+		return v * 1000
+	case "mb/s":
+		return v
+	case "kb/s":
+		return v / 1000
+	}
+	return -1
+}
+
+func nxOK(status string) float64 {
+	if strings.EqualFold(status, "ok") {
+		return 1
+	}
+	return 0
+}
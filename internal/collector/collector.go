@@ -1,7 +1,14 @@
 package collector
 
-import "netmetrics_exporter/internal/inventory"
+import (
+	"context"
 
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// Collector polls one device and writes the results into m. Implementations
+// must return promptly once ctx is cancelled or its deadline expires.
 type Collector interface {
-	Collect(device inventory.Device) error
+	Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error
 }
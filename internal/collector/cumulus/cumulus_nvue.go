@@ -0,0 +1,281 @@
+package cumulus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// DefaultPort is where the NVUE REST API listens unless the inventory sets
+// ansible_httpapi_port.
+const DefaultPort = 8765
+
+// NVUECollector polls Cumulus Linux 5.x switches through the NVUE REST API,
+// which must be enabled ("nv set system api state enabled" on releases
+// where it is off by default).
+type NVUECollector struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "cumulus",
+		Protocol: "nvue",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapInterfaceCounters,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapLLDP,
+		},
+		New: func() collector.Collector { return NVUECollector{} },
+	})
+}
+
+func (c NVUECollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	if device.Port == 0 {
+		device.Port = DefaultPort
+	}
+
+	// 1) Build & uptime; doubles as the reachability check
+	start := time.Now()
+	sys, err := nvueGet(ctx, device, "/system")
+	m.ObserveSection(device.Hostname, "system", start, err)
+	if err != nil {
+		return err
+	}
+	model := ""
+	if platform, err := nvueGet(ctx, device, "/platform"); err == nil {
+		model = nvString(platform["product-name"])
+	}
+	m.DeviceInfo.WithLabelValues(device.Hostname, model, nvString(sys["build"])).Set(1)
+	if uptime, ok := nvNumber(sys["uptime"]); ok {
+		m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime)
+	}
+
+	// 2) Interfaces
+	start = time.Now()
+	ifaces, ifErr := nvueGet(ctx, device, "/interface")
+	var ports []string
+	if ifErr == nil {
+		for name, raw := range ifaces {
+			iface, _ := raw.(map[string]interface{})
+			link, _ := iface["link"].(map[string]interface{})
+
+			up := 0.0
+			if nvString(link["state"]) == "up" {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(nvSpeedMbps(nvString(link["speed"])))
+
+			duplex := nvString(link["duplex"])
+			if duplex == "" {
+				duplex = "unknown"
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
+
+			switch nvString(iface["type"]) {
+			case "swp", "eth", "bond":
+				ports = append(ports, name)
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, ifErr)
+
+	// NVUE has no bulk view of port statistics or LLDP neighbors; the
+	// interface list leaves them out, so they are read from each port's own
+	// subtree. A port that fails is skipped and fails its section, but the
+	// other ports are still exported.
+	perPort := func(path string, fn func(port string, resp map[string]interface{})) error {
+		if ifErr != nil {
+			return ifErr
+		}
+		var firstErr error
+		for _, name := range ports {
+			resp, err := nvueGet(ctx, device, "/interface/"+url.PathEscape(name)+path)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if firstErr == nil {
+					firstErr = fmt.Errorf("port %s: %w", name, err)
+				}
+				continue
+			}
+			fn(name, resp)
+		}
+		return firstErr
+	}
+
+	// 3) Error and traffic counters
+	start = time.Now()
+	err = perPort("/link/stats", func(name string, stats map[string]interface{}) {
+		if in, ok := nvNumber(stats["in-errors"]); ok {
+			m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(in)
+		}
+		if out, ok := nvNumber(stats["out-errors"]); ok {
+			m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(out)
+		}
+		if _, ok := nvNumber(stats["in-bytes"]); !ok {
+			return
+		}
+		counter := func(key string) float64 {
+			v, _ := nvNumber(stats[key])
+			return v
+		}
+		m.SetInterfaceCounters(device.Hostname, name, metrics.InterfaceCounters{
+			InOctets:    counter("in-bytes"),
+			OutOctets:   counter("out-bytes"),
+			InPackets:   counter("in-pkts"),
+			OutPackets:  counter("out-pkts"),
+			InDiscards:  counter("in-drops"),
+			OutDiscards: counter("out-drops"),
+		})
+	})
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+	m.ObserveSection(device.Hostname, "interface_counters", start, err)
+
+	// 4) LLDP neighbors
+	start = time.Now()
+	lldpNeighbors := 0
+	err = perPort("/lldp/neighbor", func(_ string, neighbors map[string]interface{}) {
+		lldpNeighbors += len(neighbors)
+	})
+	if err == nil {
+		m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(lldpNeighbors))
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	// 5) FRR BGP sessions and OSPF neighbors in every VRF. NVUE lists BGP
+	// neighbors with their state and remote AS; OSPF only by count.
+	vrfs, vrfErr := nvueGet(ctx, device, "/vrf")
+	for _, proto := range []struct {
+		section string
+		path    string
+		gauge   *metrics.GaugeVec
+		each    func(vrf, neighbor string, n map[string]interface{})
+	}{
+		{"bgp", "/router/bgp/neighbor", m.BGPPeers, func(vrf, neighbor string, n map[string]interface{}) {
+			m.SetBGPPeer(device.Hostname, metrics.BGPPeer{
+				VRF:                vrf,
+				Address:            neighbor,
+				RemoteAS:           nvString(n["remote-as"]),
+				State:              nvString(n["state"]),
+				EstablishedSeconds: -1,
+				Flaps:              -1,
+			})
+		}},
+		{"ospf", "/router/ospf/neighbor", m.OSPFNeighbors, nil},
+	} {
+		start = time.Now()
+		err = vrfErr
+		neighbors := 0
+		for vrf := range vrfs {
+			var resp map[string]interface{}
+			resp, err = nvueGet(ctx, device, "/vrf/"+url.PathEscape(vrf)+proto.path)
+			if err != nil {
+				break
+			}
+			for neighbor, raw := range resp {
+				if proto.each != nil {
+					n, _ := raw.(map[string]interface{})
+					proto.each(vrf, neighbor, n)
+				}
+				neighbors++
+			}
+		}
+		if err == nil {
+			proto.gauge.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+		}
+		m.ObserveSection(device.Hostname, proto.section, start, err)
+	}
+
+	return ctx.Err()
+}
+
+// nvueGet reads the operational state at path. A path that does not exist,
+// such as the OSPF tree of a VRF without OSPF, reads as empty.
+func nvueGet(ctx context.Context, device inventory.Device, path string) (map[string]interface{}, error) {
+	endpoint := collector.BaseURL(device, "https") + "/nvue_v1" + path + "?rev=operational"
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(device.Username, device.Password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := collector.HTTPClient(device).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("NVUE error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("RAW NVUE BODY from %s (%s):\n%s\n", device.Hostname, path, string(body))
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]interface{}{}, nil
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("NVUE error for %s: %s", path, resp.Status)
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("NVUE returned invalid JSON for %s: %v", path, err)
+	}
+	return out, nil
+}
+
+func nvString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+func nvNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// nvSpeedMbps parses link speeds such as "100M", "25G" or "400G".
+func nvSpeedMbps(speed string) float64 {
+	mult := 0.0
+	switch {
+	case strings.HasSuffix(speed, "G"):
+		mult = 1000
+	case strings.HasSuffix(speed, "M"):
+		mult = 1
+	}
+	v, err := strconv.ParseFloat(strings.TrimRight(speed, "GM"), 64)
+	if err != nil || mult == 0 {
+		return -1
+	}
+	return v * mult
+}
@@ -0,0 +1,419 @@
+package gnmi
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+	"netmetrics_exporter/internal/metrics/metricstest"
+)
+
+// fakeServer is an in-process gNMI target. Get answers from canned
+// notifications per request path; Subscribe sends the initial
+// notifications, a sync_response, then whatever the test pushes.
+type fakeServer struct {
+	gpb.UnimplementedGNMIServer
+
+	get     map[string][]*gpb.Notification // by request path, without keys
+	failGet map[string]bool
+	initial []*gpb.Notification
+	updates chan *gpb.Notification
+	ended   chan struct{} // receives when a Subscribe stream ends
+
+	mu            sync.Mutex
+	subscriptions []*gpb.SubscriptionList
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		get:     make(map[string][]*gpb.Notification),
+		failGet: make(map[string]bool),
+		updates: make(chan *gpb.Notification),
+		ended:   make(chan struct{}, 8),
+	}
+}
+
+// start serves on a loopback port until the test ends and returns a device
+// that points at it.
+func (f *fakeServer) start(t *testing.T, options map[string]string) inventory.Device {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gpb.RegisterGNMIServer(srv, f)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	return inventory.Device{
+		Hostname: "leaf1",
+		IP:       "127.0.0.1",
+		Vendor:   "sonic",
+		Port:     l.Addr().(*net.TCPAddr).Port,
+		Scheme:   "http",
+		Username: "admin",
+		Password: "secret",
+		Options:  options,
+	}
+}
+
+func authorized(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if strings.Join(md.Get("username"), "") != "admin" || strings.Join(md.Get("password"), "") != "secret" {
+		return status.Error(codes.Unauthenticated, "bad credentials")
+	}
+	return nil
+}
+
+func (f *fakeServer) Capabilities(ctx context.Context, _ *gpb.CapabilityRequest) (*gpb.CapabilityResponse, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	return &gpb.CapabilityResponse{GNMIVersion: "0.10.0"}, nil
+}
+
+func (f *fakeServer) Get(ctx context.Context, req *gpb.GetRequest) (*gpb.GetResponse, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	resp := &gpb.GetResponse{}
+	for _, p := range req.GetPath() {
+		key := pathString(p)
+		if f.failGet[key] {
+			return nil, status.Errorf(codes.NotFound, "%s is not supported", key)
+		}
+		resp.Notification = append(resp.Notification, f.get[key]...)
+	}
+	return resp, nil
+}
+
+func (f *fakeServer) Subscribe(stream gpb.GNMI_SubscribeServer) error {
+	defer func() { f.ended <- struct{}{} }()
+	if err := authorized(stream.Context()); err != nil {
+		return err
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.subscriptions = append(f.subscriptions, req.GetSubscribe())
+	f.mu.Unlock()
+
+	for _, n := range f.initial {
+		if err := stream.Send(&gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_Update{Update: n}}); err != nil {
+			return err
+		}
+	}
+	if err := stream.Send(&gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_SyncResponse{SyncResponse: true}}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case n := <-f.updates:
+			if err := stream.Send(&gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_Update{Update: n}}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (f *fakeServer) subscriptionCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscriptions)
+}
+
+func pathString(p *gpb.Path) string {
+	var b strings.Builder
+	for _, e := range p.GetElem() {
+		b.WriteString("/" + e.GetName())
+	}
+	return b.String()
+}
+
+// path parses "/a/b[k=v]/c" into a gNMI path.
+func path(s string) *gpb.Path {
+	p := &gpb.Path{}
+	for _, part := range strings.Split(strings.Trim(s, "/"), "/") {
+		name, keys, _ := strings.Cut(part, "[")
+		e := &gpb.PathElem{Name: name}
+		if keys != "" {
+			e.Key = make(map[string]string)
+			for _, kv := range strings.Split(strings.TrimSuffix(keys, "]"), "][") {
+				k, v, _ := strings.Cut(kv, "=")
+				e.Key[k] = v
+			}
+		}
+		p.Elem = append(p.Elem, e)
+	}
+	return p
+}
+
+func update(p, json string) *gpb.Notification {
+	return &gpb.Notification{
+		Timestamp: time.Now().UnixNano(),
+		Update: []*gpb.Update{{
+			Path: path(p),
+			Val:  &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(json)}},
+		}},
+	}
+}
+
+func remove(p string) *gpb.Notification {
+	return &gpb.Notification{Timestamp: time.Now().UnixNano(), Delete: []*gpb.Path{path(p)}}
+}
+
+var (
+	ifaceUpdate = update("/interfaces/interface[name=Ethernet0]/state",
+		`{"openconfig-interfaces:oper-status":"UP","counters":{"in-errors":"5","out-errors":"2",`+
+			`"in-octets":"123456789012","out-octets":"987654321","in-unicast-pkts":"1000","in-multicast-pkts":"20","in-discards":"3"}}`)
+	ifaceDown = update("/interfaces/interface[name=Ethernet4]/state",
+		`{"oper-status":"DOWN"}`)
+	ethernetUpdate = update("/interfaces/interface[name=Ethernet0]/ethernet/state",
+		`{"port-speed":"openconfig-if-ethernet:SPEED_100GB","negotiated-duplex-mode":"FULL"}`)
+	bgpUpdate = update("/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors",
+		`{"neighbor":[{"neighbor-address":"10.0.0.1","state":{"peer-as":65001,"session-state":"ESTABLISHED",`+
+			`"last-established":"`+strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 10)+`","established-transitions":"4"}},`+
+			`{"neighbor-address":"10.0.0.3","state":{"peer-as":65003,"session-state":"ACTIVE"}}]}`)
+	afiSafiUpdate = update("/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors/neighbor[neighbor-address=10.0.0.1]/afi-safis",
+		`{"afi-safi":[{"afi-safi-name":"openconfig-bgp-types:IPV4_UNICAST","state":{"prefixes":{"received":12,"installed":10,"sent":7}}}]}`)
+	ospfUpdate = update("/network-instances/network-instance[name=default]/protocols/protocol[identifier=OSPF][name=ospf]/ospfv2/areas/area[identifier=0.0.0.0]/interfaces/interface[id=Ethernet0]/neighbors",
+		`{"neighbor":[{"router-id":"10.255.0.2","state":{"adjacency-state":"openconfig-ospf-types:FULL","last-established-time":"`+
+			strconv.FormatInt(time.Now().Add(-time.Minute).UnixNano(), 10)+`"}}]}`)
+	lldpUpdate = update("/lldp/interfaces/interface[name=Ethernet0]/neighbors",
+		`{"neighbor":[{"id":"1","state":{"system-name":"spine1"}},{"id":"2","state":{"system-name":"spine2"}}]}`)
+)
+
+func expect(t *testing.T, m *metrics.Set, want float64, name string, labels ...string) {
+	t.Helper()
+	got, ok := metricstest.Value(t, m, name, labels...)
+	if !ok {
+		t.Errorf("%s%v: no series", name, labels)
+	} else if got != want {
+		t.Errorf("%s%v = %v, want %v", name, labels, got, want)
+	}
+}
+
+func sectionErrors(t *testing.T, m *metrics.Set, section string) float64 {
+	t.Helper()
+	v, _ := metricstest.Value(t, m, "netmetrics_collect_errors_total", "hostname", "leaf1", "section", section)
+	return v
+}
+
+func collect(t *testing.T, c *GNMICollector, device inventory.Device) *metrics.Set {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m := metrics.NewSet()
+	if err := c.Collect(ctx, device, m); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	return m
+}
+
+// eventually collects until check passes, since streamed updates are
+// applied asynchronously.
+func eventually(t *testing.T, c *GNMICollector, device inventory.Device, check func(*metrics.Set) bool) *metrics.Set {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m := collect(t, c, device)
+		if check(m) || time.Now().After(deadline) {
+			return m
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGet(t *testing.T) {
+	f := newFakeServer()
+	f.get["/interfaces/interface/state"] = []*gpb.Notification{ifaceUpdate, ifaceDown}
+	f.get["/interfaces/interface/ethernet/state"] = []*gpb.Notification{ethernetUpdate}
+	f.get["/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state"] = []*gpb.Notification{bgpUpdate}
+	f.get["/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/state"] = []*gpb.Notification{afiSafiUpdate}
+	f.get["/lldp/interfaces/interface/neighbors/neighbor/state"] = []*gpb.Notification{lldpUpdate}
+	f.failGet["/network-instances/network-instance/protocols/protocol/ospfv2/areas/area/interfaces/interface/neighbors/neighbor/state"] = true
+	device := f.start(t, nil)
+
+	m := collect(t, New().(*GNMICollector), device)
+
+	expect(t, m, 1, "netmetrics_interface_up", "interface", "Ethernet0")
+	expect(t, m, 0, "netmetrics_interface_up", "interface", "Ethernet4")
+	expect(t, m, 100000, "netmetrics_interface_speed_mbps", "interface", "Ethernet0")
+	expect(t, m, -1, "netmetrics_interface_speed_mbps", "interface", "Ethernet4")
+	expect(t, m, 1, "netmetrics_interface_duplex", "interface", "Ethernet0", "duplex", "full")
+	expect(t, m, 5, "netmetrics_interface_input_errors_total", "interface", "Ethernet0")
+	expect(t, m, 2, "netmetrics_interface_output_errors_total", "interface", "Ethernet0")
+	expect(t, m, 123456789012, "netmetrics_interface_in_octets_total", "interface", "Ethernet0")
+	expect(t, m, 987654321, "netmetrics_interface_out_octets_total", "interface", "Ethernet0")
+	expect(t, m, 1020, "netmetrics_interface_in_packets_total", "interface", "Ethernet0")
+	expect(t, m, 3, "netmetrics_interface_in_discards_total", "interface", "Ethernet0")
+	expect(t, m, 2, "netmetrics_bgp_neighbors_total", "hostname", "leaf1", "vendor", "sonic")
+	expect(t, m, 2, "netmetrics_lldp_neighbors_total", "hostname", "leaf1", "vendor", "sonic")
+
+	// Per-peer BGP state and prefix counts
+	expect(t, m, 6, "netmetrics_bgp_peer_state", "peer", "10.0.0.1", "remote_as", "65001", "vrf", "default")
+	expect(t, m, 3, "netmetrics_bgp_peer_state", "peer", "10.0.0.3", "remote_as", "65003", "vrf", "default")
+	expect(t, m, 4, "netmetrics_bgp_peer_flaps_total", "peer", "10.0.0.1")
+	if v, _ := metricstest.Value(t, m, "netmetrics_bgp_peer_established_seconds", "peer", "10.0.0.1"); v < 3590 || v > 3700 {
+		t.Errorf("established seconds = %v, want about an hour", v)
+	}
+	if n := metricstest.Count(t, m, "netmetrics_bgp_peer_established_seconds", "peer", "10.0.0.3"); n != 0 {
+		t.Errorf("established seconds exported for a peer that is down")
+	}
+	expect(t, m, 12, "netmetrics_bgp_peer_prefixes_received", "peer", "10.0.0.1", "afi_safi", "ipv4-unicast")
+	expect(t, m, 10, "netmetrics_bgp_peer_prefixes_accepted", "peer", "10.0.0.1", "afi_safi", "ipv4-unicast")
+	expect(t, m, 7, "netmetrics_bgp_peer_prefixes_advertised", "peer", "10.0.0.1", "afi_safi", "ipv4-unicast")
+
+	// A section the target rejects fails alone.
+	if n := sectionErrors(t, m, "ospf"); n != 1 {
+		t.Errorf("ospf errors = %v, want 1", n)
+	}
+	if _, ok := metricstest.Value(t, m, "netmetrics_ospf_neighbors_total"); ok {
+		t.Error("OSPF neighbor count exported despite the failed Get")
+	}
+	for _, section := range []string{"system", "interfaces", "interface_errors", "interface_counters", "bgp", "lldp"} {
+		if n := sectionErrors(t, m, section); n != 0 {
+			t.Errorf("section %s: %v errors", section, n)
+		}
+	}
+}
+
+func TestGetBadCredentials(t *testing.T) {
+	f := newFakeServer()
+	device := f.start(t, nil)
+	device.Password = "wrong"
+
+	m := metrics.NewSet()
+	if err := New().Collect(context.Background(), device, m); err == nil {
+		t.Fatal("Collect succeeded with bad credentials")
+	}
+	if n := sectionErrors(t, m, "system"); n != 1 {
+		t.Errorf("system errors = %v, want 1", n)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	f := newFakeServer()
+	f.initial = []*gpb.Notification{ifaceUpdate, ethernetUpdate, bgpUpdate, ospfUpdate, lldpUpdate}
+	device := f.start(t, map[string]string{"gnmi_mode": "subscribe", "gnmi_sample_interval": "2s"})
+	c := New().(*GNMICollector)
+
+	m := collect(t, c, device)
+	expect(t, m, 1, "netmetrics_interface_up", "interface", "Ethernet0")
+	expect(t, m, 100000, "netmetrics_interface_speed_mbps", "interface", "Ethernet0")
+	expect(t, m, 5, "netmetrics_interface_input_errors_total", "interface", "Ethernet0")
+	expect(t, m, 123456789012, "netmetrics_interface_in_octets_total", "interface", "Ethernet0")
+	expect(t, m, 2, "netmetrics_bgp_neighbors_total", "hostname", "leaf1")
+	expect(t, m, 6, "netmetrics_bgp_peer_state", "peer", "10.0.0.1")
+	expect(t, m, 1, "netmetrics_ospf_neighbors_total", "hostname", "leaf1")
+	expect(t, m, 8, "netmetrics_ospf_neighbor_state", "router_id", "10.255.0.2", "area", "0.0.0.0", "interface", "Ethernet0", "vrf", "default", "instance", "ospf")
+	if v, _ := metricstest.Value(t, m, "netmetrics_ospf_neighbor_uptime_seconds", "router_id", "10.255.0.2"); v < 50 || v > 120 {
+		t.Errorf("OSPF uptime = %v, want about a minute", v)
+	}
+	expect(t, m, 2, "netmetrics_lldp_neighbors_total", "hostname", "leaf1")
+
+	// Counters are sampled, state leaves are streamed on change.
+	f.mu.Lock()
+	list := f.subscriptions[0]
+	f.mu.Unlock()
+	if list.GetMode() != gpb.SubscriptionList_STREAM {
+		t.Errorf("mode = %v, want STREAM", list.GetMode())
+	}
+	if got := list.GetPrefix().GetTarget(); got != "OC-YANG" {
+		t.Errorf("target = %q, want OC-YANG", got)
+	}
+	modes := make(map[string]*gpb.Subscription)
+	for _, sub := range list.GetSubscription() {
+		modes[pathString(sub.GetPath())] = sub
+	}
+	if sub := modes["/interfaces/interface/state/counters"]; sub.GetMode() != gpb.SubscriptionMode_SAMPLE || sub.GetSampleInterval() != uint64(2*time.Second) {
+		t.Errorf("counters subscription = %v, want SAMPLE every 2s", sub)
+	}
+	if sub := modes["/interfaces/interface/state/oper-status"]; sub.GetMode() != gpb.SubscriptionMode_ON_CHANGE {
+		t.Errorf("oper-status subscription = %v, want ON_CHANGE", sub)
+	}
+
+	// A SAMPLE update refreshes the counters.
+	f.updates <- update("/interfaces/interface[name=Ethernet0]/state/counters", `{"in-errors":"9","out-errors":"2"}`)
+	m = eventually(t, c, device, func(m *metrics.Set) bool {
+		v, _ := metricstest.Value(t, m, "netmetrics_interface_input_errors_total", "interface", "Ethernet0")
+		return v == 9
+	})
+	expect(t, m, 9, "netmetrics_interface_input_errors_total", "interface", "Ethernet0")
+
+	// ON_CHANGE updates and deletes move the state.
+	f.updates <- update("/interfaces/interface[name=Ethernet0]/state/oper-status", `"DOWN"`)
+	f.updates <- remove("/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors/neighbor[neighbor-address=10.0.0.3]")
+	f.updates <- remove("/lldp/interfaces/interface[name=Ethernet0]")
+	m = eventually(t, c, device, func(m *metrics.Set) bool {
+		v, _ := metricstest.Value(t, m, "netmetrics_lldp_neighbors_total", "hostname", "leaf1")
+		return v == 0
+	})
+	expect(t, m, 0, "netmetrics_interface_up", "interface", "Ethernet0")
+	expect(t, m, 1, "netmetrics_bgp_neighbors_total", "hostname", "leaf1")
+	if n := metricstest.Count(t, m, "netmetrics_bgp_peer_state", "peer", "10.0.0.3"); n != 0 {
+		t.Error("deleted BGP neighbor still exported")
+	}
+	expect(t, m, 0, "netmetrics_lldp_neighbors_total", "hostname", "leaf1")
+
+	// Later polls reuse the stream.
+	if n := f.subscriptionCount(); n != 1 {
+		t.Errorf("%d subscriptions, want 1", n)
+	}
+}
+
+func TestSubscribeIdleClose(t *testing.T) {
+	f := newFakeServer()
+	f.initial = []*gpb.Notification{ifaceUpdate}
+	device := f.start(t, map[string]string{"gnmi_mode": "subscribe"})
+	c := New().(*GNMICollector)
+	c.idleTimeout = 100 * time.Millisecond
+
+	collect(t, c, device)
+
+	// Nobody reads the stream, so the collector closes it.
+	select {
+	case <-f.ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle subscription was not closed")
+	}
+	c.mu.Lock()
+	sub := c.subs[device.Hostname]
+	c.mu.Unlock()
+	<-sub.done
+
+	// The next poll opens a fresh one.
+	m := collect(t, c, device)
+	expect(t, m, 1, "netmetrics_interface_up", "interface", "Ethernet0")
+	if n := f.subscriptionCount(); n != 2 {
+		t.Errorf("%d subscriptions, want 2", n)
+	}
+}
+
+func TestUnknownMode(t *testing.T) {
+	device := inventory.Device{Hostname: "leaf1", Options: map[string]string{"gnmi_mode": "poll"}}
+	m := metrics.NewSet()
+	if err := New().Collect(context.Background(), device, m); err == nil {
+		t.Fatal("Collect accepted an unknown gnmi_mode")
+	}
+	if n := sectionErrors(t, m, "system"); n != 1 {
+		t.Errorf("system errors = %v, want 1", n)
+	}
+}
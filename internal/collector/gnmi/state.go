@@ -0,0 +1,499 @@
+package gnmi
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// elem is one step of a gNMI path, with the module prefix stripped from its
+// name ("openconfig-interfaces:interface" becomes "interface").
+type elem struct {
+	name string
+	keys map[string]string
+}
+
+type ifaceState struct {
+	oper                     string
+	speed, negotiatedSpeed   string
+	duplex, negotiatedDuplex string
+	inErrors, outErrors      float64
+	hasErrors                bool
+	counters                 metrics.InterfaceCounters
+	hasCounters              bool
+}
+
+// bgpNeighbor and ospfNeighbor hold the leaves of one session; the
+// timestamps are the raw timeticks64 values, 0 until reported.
+type bgpNeighbor struct {
+	peer            metrics.BGPPeer
+	lastEstablished float64
+	families        map[string]*metrics.BGPFamily // by afi-safi-name
+}
+
+type ospfNeighbor struct {
+	neighbor        metrics.OSPFNeighbor
+	lastEstablished float64
+}
+
+// state is the subset of the OpenConfig tree the collector exports. Get
+// fills a fresh one on every poll; a subscription keeps one up to date.
+type state struct {
+	ifaces   map[string]*ifaceState
+	bgpPeers map[string]*bgpNeighbor  // network-instance|neighbor-address
+	ospf     map[string]*ospfNeighbor // network-instance|area|interface|router-id
+	lldp     map[string]bool          // interface|neighbor id
+}
+
+func newState() *state {
+	return &state{
+		ifaces:   make(map[string]*ifaceState),
+		bgpPeers: make(map[string]*bgpNeighbor),
+		ospf:     make(map[string]*ospfNeighbor),
+		lldp:     make(map[string]bool),
+	}
+}
+
+// apply merges one notification into the state.
+func (s *state) apply(n *gpb.Notification) {
+	prefix := toElems(n.GetPrefix())
+	for _, p := range n.GetDelete() {
+		s.delete(join(prefix, toElems(p)))
+	}
+	for _, u := range n.GetUpdate() {
+		path := join(prefix, toElems(u.GetPath()))
+		walk(path, decodeValue(u.GetVal()), s.set)
+	}
+}
+
+// set stores a single leaf. Leaves outside the exported subset are ignored.
+func (s *state) set(path []elem, value interface{}) {
+	names := make([]string, len(path))
+	for i, e := range path {
+		names[i] = e.name
+	}
+	at := func(want ...string) bool {
+		if len(names) < len(want) {
+			return false
+		}
+		for i, w := range want {
+			if names[i] != w {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case at("interfaces", "interface") && len(names) > 2:
+		iface := s.iface(path[1].keys["name"])
+		switch strings.Join(names[2:], "/") {
+		case "state/oper-status":
+			iface.oper = identity(value)
+		case "state/counters/in-errors":
+			iface.inErrors, iface.hasErrors = number(value), true
+		case "state/counters/out-errors":
+			iface.outErrors, iface.hasErrors = number(value), true
+		case "state/counters/in-octets":
+			iface.counters.InOctets, iface.hasCounters = number(value), true
+		case "state/counters/out-octets":
+			iface.counters.OutOctets, iface.hasCounters = number(value), true
+		case "state/counters/in-pkts":
+			iface.counters.InPackets = number(value)
+		case "state/counters/out-pkts":
+			iface.counters.OutPackets = number(value)
+		case "state/counters/in-unicast-pkts":
+			iface.counters.InUnicastPackets = number(value)
+		case "state/counters/out-unicast-pkts":
+			iface.counters.OutUnicastPackets = number(value)
+		case "state/counters/in-multicast-pkts":
+			iface.counters.InMulticastPackets = number(value)
+		case "state/counters/out-multicast-pkts":
+			iface.counters.OutMulticastPackets = number(value)
+		case "state/counters/in-broadcast-pkts":
+			iface.counters.InBroadcastPackets = number(value)
+		case "state/counters/out-broadcast-pkts":
+			iface.counters.OutBroadcastPackets = number(value)
+		case "state/counters/in-discards":
+			iface.counters.InDiscards = number(value)
+		case "state/counters/out-discards":
+			iface.counters.OutDiscards = number(value)
+		case "ethernet/state/port-speed":
+			iface.speed = identity(value)
+		case "ethernet/state/negotiated-port-speed":
+			iface.negotiatedSpeed = identity(value)
+		case "ethernet/state/duplex-mode":
+			iface.duplex = identity(value)
+		case "ethernet/state/negotiated-duplex-mode":
+			iface.negotiatedDuplex = identity(value)
+		}
+
+	case at("network-instances", "network-instance", "protocols", "protocol", "bgp", "neighbors", "neighbor"):
+		n := s.bgpNeighbor(path[1].keys["name"], path[6].keys["neighbor-address"])
+		switch leaf := strings.Join(names[7:], "/"); leaf {
+		case "state/peer-as":
+			n.peer.RemoteAS = identity(value)
+		case "state/session-state":
+			n.peer.State = identity(value)
+		case "state/last-established":
+			n.lastEstablished = number(value)
+		case "state/established-transitions":
+			n.peer.Flaps = number(value)
+		case "afi-safis/afi-safi/state/prefixes/received",
+			"afi-safis/afi-safi/state/prefixes/installed",
+			"afi-safis/afi-safi/state/prefixes/sent":
+			f := n.family(path[8].keys["afi-safi-name"])
+			switch leaf[strings.LastIndex(leaf, "/")+1:] {
+			case "received":
+				f.Received = number(value)
+			case "installed":
+				f.Accepted = number(value)
+			case "sent":
+				f.Advertised = number(value)
+			}
+		}
+
+	case at("network-instances", "network-instance", "protocols", "protocol", "ospfv2", "areas", "area", "interfaces", "interface", "neighbors", "neighbor"):
+		n := s.ospfNeighbor(path)
+		switch strings.Join(names[11:], "/") {
+		case "state/adjacency-state":
+			n.neighbor.State = identity(value)
+		case "state/last-established-time":
+			n.lastEstablished = number(value)
+		}
+
+	case at("lldp", "interfaces", "interface", "neighbors", "neighbor"):
+		s.lldp[path[2].keys["name"]+"|"+path[4].keys["id"]] = true
+	}
+}
+
+// delete drops the interfaces, peers or neighbors at or below path. A
+// path that stops above the list entries drops every entry below it.
+func (s *state) delete(path []elem) {
+	key := func(i int, k string) string {
+		if i >= len(path) {
+			return ""
+		}
+		return path[i].keys[k]
+	}
+	switch {
+	case len(path) == 2 && path[0].name == "interfaces":
+		delete(s.ifaces, key(1, "name"))
+	case len(path) >= 1 && path[0].name == "network-instances":
+		ni := ""
+		if len(path) >= 2 {
+			ni = key(1, "name") + "|"
+		}
+		switch {
+		case len(path) <= 4:
+			dropPrefix(s.bgpPeers, ni)
+			dropPrefix(s.ospf, ni)
+		case len(path) == 7 && path[4].name == "bgp":
+			delete(s.bgpPeers, ni+key(6, "neighbor-address"))
+		case len(path) == 11 && path[4].name == "ospfv2":
+			delete(s.ospf, ni+strings.Join([]string{key(6, "identifier"), key(8, "id"), key(10, "router-id")}, "|"))
+		}
+	case len(path) >= 1 && path[0].name == "lldp":
+		switch {
+		case len(path) < 3:
+			dropPrefix(s.lldp, "")
+		case len(path) == 3:
+			dropPrefix(s.lldp, key(2, "name")+"|")
+		case len(path) == 5:
+			delete(s.lldp, key(2, "name")+"|"+key(4, "id"))
+		}
+	}
+}
+
+func (s *state) iface(name string) *ifaceState {
+	iface, ok := s.ifaces[name]
+	if !ok {
+		iface = &ifaceState{}
+		s.ifaces[name] = iface
+	}
+	return iface
+}
+
+func (s *state) bgpNeighbor(instance, address string) *bgpNeighbor {
+	key := instance + "|" + address
+	n, ok := s.bgpPeers[key]
+	if !ok {
+		n = &bgpNeighbor{
+			peer:     metrics.BGPPeer{VRF: instance, Address: address, Flaps: -1},
+			families: make(map[string]*metrics.BGPFamily),
+		}
+		s.bgpPeers[key] = n
+	}
+	return n
+}
+
+func (n *bgpNeighbor) family(name string) *metrics.BGPFamily {
+	f, ok := n.families[name]
+	if !ok {
+		f = &metrics.BGPFamily{AFISAFI: afiSafi(name), Received: -1, Accepted: -1, Advertised: -1}
+		n.families[name] = f
+	}
+	return f
+}
+
+// ospfNeighbor returns the entry for the neighbor at path, which runs
+// from network-instances down to the neighbor list.
+func (s *state) ospfNeighbor(path []elem) *ospfNeighbor {
+	key := strings.Join([]string{path[1].keys["name"], path[6].keys["identifier"], path[8].keys["id"], path[10].keys["router-id"]}, "|")
+	n, ok := s.ospf[key]
+	if !ok {
+		n = &ospfNeighbor{neighbor: metrics.OSPFNeighbor{
+			VRF:       path[1].keys["name"],
+			Instance:  path[3].keys["name"],
+			Area:      path[6].keys["identifier"],
+			Interface: path[8].keys["id"],
+			RouterID:  path[10].keys["router-id"],
+		}}
+		s.ospf[key] = n
+	}
+	return n
+}
+
+// write exports the given sections of the state into m.
+func (s *state) write(device inventory.Device, m *metrics.Set, sections ...string) {
+	for _, section := range sections {
+		switch section {
+		case collector.CapInterfaces:
+			for name, iface := range s.ifaces {
+				if iface.oper == "" {
+					continue
+				}
+				up := 0.0
+				if iface.oper == "UP" {
+					up = 1
+				}
+				m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+
+				speed := speedMbps(iface.negotiatedSpeed)
+				if speed < 0 {
+					speed = speedMbps(iface.speed)
+				}
+				m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(speed)
+
+				duplex := iface.negotiatedDuplex
+				if duplex == "" {
+					duplex = iface.duplex
+				}
+				if duplex == "" {
+					duplex = "unknown"
+				}
+				m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, strings.ToLower(duplex)).Set(1)
+			}
+		case collector.CapInterfaceErrors:
+			for name, iface := range s.ifaces {
+				if iface.hasErrors {
+					m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(iface.inErrors)
+					m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(iface.outErrors)
+				}
+			}
+		case collector.CapInterfaceCounters:
+			for name, iface := range s.ifaces {
+				if iface.hasCounters {
+					m.SetInterfaceCounters(device.Hostname, name, iface.counters)
+				}
+			}
+		case collector.CapBGP:
+			for _, n := range s.bgpPeers {
+				peer := n.peer
+				peer.EstablishedSeconds = secondsSince(n.lastEstablished)
+				peer.Families = nil
+				for _, f := range n.families {
+					peer.Families = append(peer.Families, *f)
+				}
+				m.SetBGPPeer(device.Hostname, peer)
+			}
+			m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(s.bgpPeers)))
+		case collector.CapOSPF:
+			for _, n := range s.ospf {
+				neighbor := n.neighbor
+				neighbor.UptimeSeconds = secondsSince(n.lastEstablished)
+				m.SetOSPFNeighbor(device.Hostname, neighbor)
+			}
+			m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(s.ospf)))
+		case collector.CapLLDP:
+			m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(s.lldp)))
+		}
+	}
+}
+
+// listKeys names the key leaves of the lists the collector reads, so the
+// entries of a JSON-encoded subtree can be addressed like gNMI path keys.
+var listKeys = map[string][]string{
+	"interface":        {"name", "id"},
+	"network-instance": {"name"},
+	"protocol":         {"identifier", "name"},
+	"area":             {"identifier"},
+	"neighbor":         {"neighbor-address", "router-id", "id"},
+	"afi-safi":         {"afi-safi-name"},
+}
+
+// walk calls fn for every leaf below path. Scalar values are leaves
+// themselves; JSON objects and lists are descended into.
+func walk(path []elem, value interface{}, fn func([]elem, interface{})) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		fn(path, value)
+		return
+	}
+	for k, child := range obj {
+		name := stripModule(k)
+		if list, ok := child.([]interface{}); ok {
+			for _, item := range list {
+				entry, ok := item.(map[string]interface{})
+				if !ok {
+					fn(join(path, []elem{{name: name}}), item) // leaf-list
+					continue
+				}
+				keys := make(map[string]string)
+				for _, kn := range listKeys[name] {
+					if kv, ok := entry[kn]; ok {
+						keys[kn] = identity(kv)
+					}
+				}
+				walk(join(path, []elem{{name: name, keys: keys}}), entry, fn)
+			}
+			continue
+		}
+		walk(join(path, []elem{{name: name}}), child, fn)
+	}
+}
+
+func toElems(p *gpb.Path) []elem {
+	var out []elem
+	for _, e := range p.GetElem() {
+		out = append(out, elem{name: stripModule(e.GetName()), keys: e.GetKey()})
+	}
+	return out
+}
+
+// join returns a new path, so callers never share a backing array.
+func join(a, b []elem) []elem {
+	out := make([]elem, 0, len(a)+len(b))
+	return append(append(out, a...), b...)
+}
+
+// decodeValue turns a TypedValue into a Go scalar, or into the decoded JSON
+// document for JSON and JSON_IETF encodings.
+func decodeValue(v *gpb.TypedValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *gpb.TypedValue_JsonIetfVal:
+		return decodeJSON(val.JsonIetfVal)
+	case *gpb.TypedValue_JsonVal:
+		return decodeJSON(val.JsonVal)
+	case *gpb.TypedValue_StringVal:
+		return val.StringVal
+	case *gpb.TypedValue_UintVal:
+		return float64(val.UintVal)
+	case *gpb.TypedValue_IntVal:
+		return float64(val.IntVal)
+	case *gpb.TypedValue_BoolVal:
+		return val.BoolVal
+	case *gpb.TypedValue_DoubleVal:
+		return val.DoubleVal
+	case *gpb.TypedValue_FloatVal:
+		return float64(val.FloatVal)
+	}
+	return nil
+}
+
+func decodeJSON(data []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func stripModule(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// identity returns a leaf as a string, without the module prefix that
+// identityref values carry in JSON_IETF.
+func identity(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return stripModule(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+// number reads a leaf that may be a JSON number or, for 64-bit counters in
+// JSON_IETF, a string.
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// speedMbps converts OpenConfig ETHERNET_SPEED identities such as
+// SPEED_10GB or SPEED_2500MB to Mbps, or -1 if unknown.
+func speedMbps(speed string) float64 {
+	s := strings.TrimPrefix(speed, "SPEED_")
+	mult := 0.0
+	switch {
+	case strings.HasSuffix(s, "GB"):
+		mult, s = 1000, strings.TrimSuffix(s, "GB")
+	case strings.HasSuffix(s, "MB"):
+		mult, s = 1, strings.TrimSuffix(s, "MB")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || mult == 0 {
+		return -1
+	}
+	return v * mult
+}
+
+// afiSafi spells an OpenConfig AFI_SAFI_TYPE identity such as IPV4_UNICAST
+// or L2VPN_EVPN the way the other collectors label families.
+func afiSafi(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(stripModule(name), "_", "-"))
+	if name == "l2vpn-evpn" {
+		return "evpn"
+	}
+	return name
+}
+
+// secondsSince converts a timeticks64 timestamp to the time elapsed since
+// then, or -1 when it is unset. The OpenConfig models count nanoseconds
+// since the epoch; releases before that counted seconds.
+func secondsSince(ticks float64) float64 {
+	if ticks <= 0 {
+		return -1
+	}
+	t := time.Unix(int64(ticks), 0)
+	if ticks > 1e12 {
+		t = time.Unix(0, int64(ticks))
+	}
+	return time.Since(t).Seconds()
+}
+
+func dropPrefix[V any](m map[string]V, prefix string) {
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			delete(m, k)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package gnmi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+const (
+	// defaultSampleInterval applies when neither netmetrics_gnmi_sample_interval
+	// nor a per-device scrape interval is set.
+	defaultSampleInterval = 30 * time.Second
+
+	// defaultIdleTimeout closes a stream nobody has read for a while, e.g.
+	// after its device left the inventory.
+	defaultIdleTimeout = 5 * time.Minute
+)
+
+// Leaves that change rarely are streamed ON_CHANGE; counters are sampled.
+var (
+	onChangePaths = []string{
+		"/interfaces/interface/state/oper-status",
+		"/interfaces/interface/ethernet/state",
+		"/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/peer-as",
+		"/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state",
+		"/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/last-established",
+		"/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/established-transitions",
+		"/network-instances/network-instance/protocols/protocol/ospfv2/areas/area/interfaces/interface/neighbors/neighbor/state/adjacency-state",
+		"/network-instances/network-instance/protocols/protocol/ospfv2/areas/area/interfaces/interface/neighbors/neighbor/state/last-established-time",
+		"/lldp/interfaces/interface/neighbors/neighbor/state",
+	}
+	samplePaths = []string{
+		"/interfaces/interface/state/counters",
+		"/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/state/prefixes",
+	}
+
+	// streamedSections are served from the stream's state; the stream
+	// itself stands for "system".
+	streamedSections = []string{
+		collector.CapInterfaces,
+		collector.CapInterfaceErrors,
+		collector.CapInterfaceCounters,
+		collector.CapBGP,
+		collector.CapOSPF,
+		collector.CapLLDP,
+	}
+)
+
+// subscription is one long-lived Subscribe stream and the state it has
+// built up so far.
+type subscription struct {
+	device      inventory.Device
+	idleTimeout time.Duration
+	cancel      context.CancelFunc
+	synced      chan struct{} // closed on the first sync_response
+	syncOnce    sync.Once
+	done        chan struct{} // closed when the stream ends; err says why
+
+	mu       sync.Mutex
+	state    *state
+	err      error
+	lastRead time.Time
+}
+
+func (c *GNMICollector) collectSubscribe(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// Waiting for the initial sync is the reachability check; afterwards
+	// the stream is already in sync and this returns at once.
+	start := time.Now()
+	sub := c.subscription(device)
+	select {
+	case <-sub.synced:
+	case <-sub.done:
+	case <-ctx.Done():
+		m.ObserveSection(device.Hostname, "system", start, ctx.Err())
+		return ctx.Err()
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	m.ObserveSection(device.Hostname, "system", start, sub.err)
+	if sub.err != nil {
+		return sub.err
+	}
+	sub.lastRead = time.Now()
+
+	sub.state.write(device, m, streamedSections...)
+	for _, section := range streamedSections {
+		m.ObserveSection(device.Hostname, section, start, nil)
+	}
+	return ctx.Err()
+}
+
+// subscription returns the running stream for device, starting a new one
+// if there is none, the last one ended, or the device's settings changed.
+func (c *GNMICollector) subscription(device inventory.Device) *subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for host, sub := range c.subs {
+		if sub.finished() {
+			delete(c.subs, host)
+		}
+	}
+	if sub, ok := c.subs[device.Hostname]; ok {
+		if reflect.DeepEqual(sub.device, device) {
+			return sub
+		}
+		sub.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{
+		device:      device,
+		idleTimeout: c.idleTimeout,
+		cancel:      cancel,
+		synced:      make(chan struct{}),
+		done:        make(chan struct{}),
+		state:       newState(),
+		lastRead:    time.Now(),
+	}
+	c.subs[device.Hostname] = sub
+	go sub.run(ctx)
+	go sub.watchIdle()
+	return sub
+}
+
+func (s *subscription) finished() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *subscription) run(ctx context.Context) {
+	err := s.stream(ctx)
+	if err == nil || ctx.Err() != nil {
+		err = errors.New("gNMI subscription closed")
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	close(s.done)
+}
+
+func (s *subscription) stream(ctx context.Context) error {
+	req, err := subscribeRequest(s.device)
+	if err != nil {
+		return err
+	}
+
+	conn, err := Dial(s.device)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := gpb.NewGNMIClient(conn).Subscribe(withCredentials(ctx, s.device))
+	if err != nil {
+		return fmt.Errorf("gNMI Subscribe: %w", err)
+	}
+	if err := stream.Send(req); err != nil {
+		return fmt.Errorf("gNMI Subscribe: %w", err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("gNMI Subscribe: %w", err)
+		}
+		switch r := resp.GetResponse().(type) {
+		case *gpb.SubscribeResponse_Update:
+			s.mu.Lock()
+			s.state.apply(r.Update)
+			s.mu.Unlock()
+		case *gpb.SubscribeResponse_SyncResponse:
+			s.syncOnce.Do(func() { close(s.synced) })
+		}
+	}
+}
+
+func (s *subscription) watchIdle() {
+	ticker := time.NewTicker(s.idleTimeout / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			idle := time.Since(s.lastRead)
+			s.mu.Unlock()
+			if idle > s.idleTimeout {
+				log.Printf("[INFO] Closing idle gNMI subscription to %s", s.device.Hostname)
+				s.cancel()
+				return
+			}
+		}
+	}
+}
+
+func subscribeRequest(device inventory.Device) (*gpb.SubscribeRequest, error) {
+	encoding, err := encodingOf(device)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := device.Interval
+	if v := device.Options["gnmi_sample_interval"]; v != "" {
+		if interval, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("invalid gnmi_sample_interval %q: %v", v, err)
+		}
+	}
+	if interval <= 0 {
+		interval = defaultSampleInterval
+	}
+
+	list := &gpb.SubscriptionList{
+		Prefix:   prefixOf(device),
+		Mode:     gpb.SubscriptionList_STREAM,
+		Encoding: encoding,
+	}
+	for _, p := range onChangePaths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{
+			Path: parsePath(p),
+			Mode: gpb.SubscriptionMode_ON_CHANGE,
+		})
+	}
+	for _, p := range samplePaths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{
+			Path:           parsePath(p),
+			Mode:           gpb.SubscriptionMode_SAMPLE,
+			SampleInterval: uint64(interval.Nanoseconds()),
+		})
+	}
+	return &gpb.SubscribeRequest{
+		Request: &gpb.SubscribeRequest_Subscribe{Subscribe: list},
+	}, nil
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/inventory"
+)
+
+// The HTTP clients are shared by every collector so connections are reused.
+// The overall timeout is a backstop; callers bound requests with their
+// context. Certificates are only verified for devices that ask for it, since
+// most network gear ships with self-signed ones.
+var (
+	insecureClient  = newHTTPClient(false)
+	verifyingClient = newHTTPClient(true)
+)
+
+func newHTTPClient(verify bool) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: !verify}
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: t,
+	}
+}
+
+// HTTPClient returns the client to use for device, honouring its
+// ValidateCerts setting.
+func HTTPClient(device inventory.Device) *http.Client {
+	if device.ValidateCerts {
+		return verifyingClient
+	}
+	return insecureClient
+}
+
+// BaseURL returns scheme://host[:port] for the management API of device.
+// defaultScheme applies when the inventory does not set one; the port is
+// left out when unset so the scheme default (80 or 443) is used.
+func BaseURL(device inventory.Device, defaultScheme string) string {
+	scheme := device.Scheme
+	if scheme == "" {
+		scheme = defaultScheme
+	}
+	host := device.IP
+	if device.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(device.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+// SSHAddress returns host:port for SSH to device, port 22 unless the
+// inventory overrides it.
+func SSHAddress(device inventory.Device) string {
+	port := device.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(device.IP, strconv.Itoa(port))
+}
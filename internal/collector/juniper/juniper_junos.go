@@ -0,0 +1,257 @@
+package juniper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/collector/netconf"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+type JunosCollector struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "juniper",
+		Protocol: "netconf",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapLLDP,
+			collector.CapCPU,
+			collector.CapMemory,
+		},
+		New: func() collector.Collector { return JunosCollector{} },
+	})
+}
+
+// Junos pads most text nodes with newlines, so every string is trimmed
+// before use.
+
+type softwareInformation struct {
+	Model   string `xml:"product-model"`
+	Version string `xml:"junos-version"`
+	// Dual-RE chassis answer once per routing engine.
+	PerRE []softwareInformation `xml:"multi-routing-engine-item>software-information"`
+}
+
+type routeEngineInformation struct {
+	RouteEngines []routeEngine `xml:"route-engine"`
+}
+
+type routeEngine struct {
+	Mastership string `xml:"mastership-state"`
+	CPUIdle    string `xml:"cpu-idle"`
+	MemoryUtil string `xml:"memory-buffer-utilization"`
+	MemoryDRAM string `xml:"memory-dram-size"`
+	UpTime     struct {
+		Seconds float64 `xml:"seconds,attr"`
+	} `xml:"up-time"`
+}
+
+// master returns the master routing engine, or the only one on boxes that
+// do not report a mastership state.
+func (info routeEngineInformation) master() (routeEngine, bool) {
+	for _, r := range info.RouteEngines {
+		if strings.TrimSpace(r.Mastership) == "master" {
+			return r, true
+		}
+	}
+	if len(info.RouteEngines) == 1 {
+		return info.RouteEngines[0], true
+	}
+	return routeEngine{}, false
+}
+
+type interfaceInformation struct {
+	Interfaces []struct {
+		Name         string  `xml:"name"`
+		OperStatus   string  `xml:"oper-status"`
+		Speed        string  `xml:"speed"`
+		LinkMode     string  `xml:"link-mode"`
+		InputErrors  float64 `xml:"input-error-count"`
+		OutputErrors float64 `xml:"output-error-count"`
+	} `xml:"physical-interface"`
+}
+
+type bgpInformation struct {
+	PeerCount string     `xml:"peer-count"`
+	Peers     []struct{} `xml:"bgp-peer"`
+}
+
+type ospfNeighborInformation struct {
+	Neighbors []struct{} `xml:"ospf-neighbor"`
+}
+
+type lldpNeighborsInformation struct {
+	Neighbors []struct{} `xml:"lldp-neighbor-information"`
+}
+
+func (c JunosCollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// One NETCONF session carries every RPC. Opening it and reading the
+	// software information double as the reachability check.
+	start := time.Now()
+	session, err := netconf.Dial(ctx, device)
+	if err != nil {
+		m.ObserveSection(device.Hostname, "system", start, err)
+		return err
+	}
+	defer session.Close()
+
+	// 1) Model & version
+	var sw softwareInformation
+	err = call(ctx, session, device, "<get-software-information/>", &sw)
+	m.ObserveSection(device.Hostname, "system", start, err)
+	if err != nil {
+		return err
+	}
+	if len(sw.PerRE) > 0 {
+		sw = sw.PerRE[0]
+	}
+	m.DeviceInfo.WithLabelValues(device.Hostname, strings.TrimSpace(sw.Model), strings.TrimSpace(sw.Version)).Set(1)
+
+	// 2) Routing engine: uptime, CPU and memory of the master RE
+	start = time.Now()
+	var re routeEngineInformation
+	err = call(ctx, session, device, "<get-route-engine-information/>", &re)
+	if err == nil {
+		if r, ok := re.master(); ok {
+			m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(r.UpTime.Seconds)
+			if idle, err := strconv.ParseFloat(strings.TrimSpace(r.CPUIdle), 64); err == nil {
+				m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(100 - idle)
+			}
+			util, err := strconv.ParseFloat(strings.TrimSpace(r.MemoryUtil), 64)
+			if total, ok := parseMB(r.MemoryDRAM); ok && err == nil {
+				total *= 1024 * 1024
+				m.SetMemory(device.Hostname, device.Vendor, total, total*util/100)
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+	m.ObserveSection(device.Hostname, "memory", start, err)
+
+	// 3) Interfaces and their error counters
+	start = time.Now()
+	var ifaces interfaceInformation
+	err = call(ctx, session, device, "<get-interface-information><statistics/></get-interface-information>", &ifaces)
+	if err == nil {
+		for _, iface := range ifaces.Interfaces {
+			name := strings.TrimSpace(iface.Name)
+
+			up := 0.0
+			if strings.TrimSpace(iface.OperStatus) == "up" {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(parseSpeedMbps(iface.Speed))
+
+			duplex := "unknown"
+			if mode := strings.TrimSpace(iface.LinkMode); mode != "" {
+				duplex = strings.TrimSuffix(strings.ToLower(mode), "-duplex")
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
+
+			m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(iface.InputErrors)
+			m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(iface.OutputErrors)
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	// 4) BGP peers
+	start = time.Now()
+	var bgp bgpInformation
+	err = call(ctx, session, device, "<get-bgp-summary-information/>", &bgp)
+	if err == nil {
+		peers := float64(len(bgp.Peers))
+		if n, err := strconv.ParseFloat(strings.TrimSpace(bgp.PeerCount), 64); err == nil {
+			peers = n
+		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(peers)
+	}
+	m.ObserveSection(device.Hostname, "bgp", start, err)
+
+	// 5) OSPF neighbors
+	start = time.Now()
+	var ospf ospfNeighborInformation
+	err = call(ctx, session, device, "<get-ospf-neighbor-information/>", &ospf)
+	if errors.Is(err, netconf.ErrNoData) {
+		err = nil // OSPF not running
+	}
+	if err == nil {
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(ospf.Neighbors)))
+	}
+	m.ObserveSection(device.Hostname, "ospf", start, err)
+
+	// 6) LLDP neighbors
+	start = time.Now()
+	var lldp lldpNeighborsInformation
+	err = call(ctx, session, device, "<get-lldp-neighbors-information/>", &lldp)
+	if errors.Is(err, netconf.ErrNoData) {
+		err = nil // LLDP not running
+	}
+	if err == nil {
+		m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(lldp.Neighbors)))
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	return ctx.Err()
+}
+
+// call runs one RPC and, with NETMETRICS_DEBUG=1, dumps the decoded reply.
+func call(ctx context.Context, session *netconf.Session, device inventory.Device, rpc string, v interface{}) error {
+	if err := session.Call(ctx, rpc, v); err != nil {
+		return fmt.Errorf("%s: %w", rpc, err)
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("DEBUG NETCONF RESULT from %s (%s): %+v\n", device.Hostname, rpc, v)
+	}
+	return nil
+}
+
+// parseSpeedMbps turns Junos speeds such as "1000mbps", "10Gbps" or "100G"
+// into Mbps. "Auto", "Unlimited" and anything else unknown give -1.
+func parseSpeedMbps(speed string) float64 {
+	s := strings.ToLower(strings.TrimSpace(speed))
+	s = strings.TrimSuffix(s, "bps")
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "g"):
+		mult, s = 1000, strings.TrimSuffix(s, "g")
+	case strings.HasSuffix(s, "m"):
+		s = strings.TrimSuffix(s, "m")
+	case strings.HasSuffix(s, "k"):
+		mult, s = 0.001, strings.TrimSuffix(s, "k")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return -1
+	}
+	return v * mult
+}
+
+// parseMB reads Junos memory sizes such as "3584 MB".
+func parseMB(size string) (float64, bool) {
+	fields := strings.Fields(size)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	if len(fields) > 1 && strings.EqualFold(fields[1], "GB") {
+		v *= 1024
+	}
+	return v, true
+}
@@ -0,0 +1,212 @@
+package juniper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"netmetrics_exporter/internal/collector/netconf/netconftest"
+	"netmetrics_exporter/internal/metrics"
+	"netmetrics_exporter/internal/metrics/metricstest"
+)
+
+// Replies as a vMX answers them, trimmed to the leaves the collector reads.
+var junosReplies = map[string]string{
+	"get-software-information": `<software-information>
+<host-name>vmx1</host-name>
+<product-model>vmx</product-model>
+<junos-version>23.2R1.14</junos-version>
+</software-information>`,
+
+	"get-route-engine-information": `<route-engine-information xmlns="http://xml.juniper.net/junos/23.2R1.14/junos-chassis">
+<route-engine>
+<slot>0</slot>
+<mastership-state>master</mastership-state>
+<memory-dram-size>16384 MB</memory-dram-size>
+<memory-buffer-utilization>25</memory-buffer-utilization>
+<cpu-idle>93</cpu-idle>
+<up-time seconds="86400">1 day</up-time>
+</route-engine>
+<route-engine>
+<slot>1</slot>
+<mastership-state>backup</mastership-state>
+<memory-dram-size>16384 MB</memory-dram-size>
+<memory-buffer-utilization>10</memory-buffer-utilization>
+<cpu-idle>99</cpu-idle>
+<up-time seconds="3600">1 hour</up-time>
+</route-engine>
+</route-engine-information>`,
+
+	"get-interface-information": `<interface-information xmlns="http://xml.juniper.net/junos/23.2R1.14/junos-interface">
+<physical-interface>
+<name>
+ge-0/0/0
+</name>
+<oper-status>
+up
+</oper-status>
+<speed>1000mbps</speed>
+<link-mode>Full-duplex</link-mode>
+<input-error-list><input-errors>3</input-errors></input-error-list>
+<input-error-count>3</input-error-count>
+<output-error-count>1</output-error-count>
+</physical-interface>
+<physical-interface>
+<name>xe-0/0/1</name>
+<oper-status>down</oper-status>
+<speed>10Gbps</speed>
+</physical-interface>
+</interface-information>`,
+
+	"get-bgp-summary-information": `<bgp-information xmlns="http://xml.juniper.net/junos/23.2R1.14/junos-routing">
+<group-count>1</group-count>
+<peer-count>3</peer-count>
+<bgp-peer><peer-address>10.0.0.1</peer-address></bgp-peer>
+<bgp-peer><peer-address>10.0.0.2</peer-address></bgp-peer>
+<bgp-peer><peer-address>10.0.0.3</peer-address></bgp-peer>
+</bgp-information>`,
+
+	"get-ospf-neighbor-information": `<ospf-neighbor-information xmlns="http://xml.juniper.net/junos/23.2R1.14/junos-routing">
+<ospf-neighbor><neighbor-address>10.1.0.1</neighbor-address><ospf-neighbor-state>Full</ospf-neighbor-state></ospf-neighbor>
+<ospf-neighbor><neighbor-address>10.1.0.5</neighbor-address><ospf-neighbor-state>Full</ospf-neighbor-state></ospf-neighbor>
+</ospf-neighbor-information>`,
+
+	"get-lldp-neighbors-information": `<lldp-neighbors-information>
+<lldp-neighbor-information><lldp-local-port-id>ge-0/0/0</lldp-local-port-id></lldp-neighbor-information>
+</lldp-neighbors-information>`,
+}
+
+func collect(t *testing.T, srv *netconftest.Server) (*metrics.Set, error) {
+	t.Helper()
+	device := srv.Start(t)
+	device.Hostname = "vmx1"
+	device.Vendor = "juniper"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	m := metrics.NewSet()
+	return m, JunosCollector{}.Collect(ctx, device, m)
+}
+
+func expect(t *testing.T, m *metrics.Set, want float64, name string, labels ...string) {
+	t.Helper()
+	got, ok := metricstest.Value(t, m, name, labels...)
+	if !ok {
+		t.Errorf("%s%v: no series", name, labels)
+	} else if got != want {
+		t.Errorf("%s%v = %v, want %v", name, labels, got, want)
+	}
+}
+
+func sectionErrors(t *testing.T, m *metrics.Set, section string) float64 {
+	t.Helper()
+	v, _ := metricstest.Value(t, m, "netmetrics_collect_errors_total", "hostname", "vmx1", "section", section)
+	return v
+}
+
+func TestCollect(t *testing.T) {
+	m, err := collect(t, &netconftest.Server{Replies: junosReplies})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	expect(t, m, 1, "netmetrics_device_info", "model", "vmx", "version", "23.2R1.14")
+	expect(t, m, 86400, "netmetrics_device_uptime_seconds", "hostname", "vmx1")
+	expect(t, m, 7, "netmetrics_cpu_usage_percent", "vendor", "juniper")
+	expect(t, m, 16384*1024*1024, "netmetrics_memory_total_bytes", "hostname", "vmx1")
+	expect(t, m, 4096*1024*1024, "netmetrics_memory_used_bytes", "hostname", "vmx1")
+	expect(t, m, 25, "netmetrics_memory_usage_percent", "hostname", "vmx1")
+
+	expect(t, m, 1, "netmetrics_interface_up", "interface", "ge-0/0/0")
+	expect(t, m, 0, "netmetrics_interface_up", "interface", "xe-0/0/1")
+	expect(t, m, 1000, "netmetrics_interface_speed_mbps", "interface", "ge-0/0/0")
+	expect(t, m, 10000, "netmetrics_interface_speed_mbps", "interface", "xe-0/0/1")
+	expect(t, m, 1, "netmetrics_interface_duplex", "interface", "ge-0/0/0", "duplex", "full")
+	expect(t, m, 1, "netmetrics_interface_duplex", "interface", "xe-0/0/1", "duplex", "unknown")
+	expect(t, m, 3, "netmetrics_interface_input_errors_total", "interface", "ge-0/0/0")
+	expect(t, m, 1, "netmetrics_interface_output_errors_total", "interface", "ge-0/0/0")
+
+	expect(t, m, 3, "netmetrics_bgp_neighbors_total", "hostname", "vmx1")
+	expect(t, m, 2, "netmetrics_ospf_neighbors_total", "hostname", "vmx1")
+	expect(t, m, 1, "netmetrics_lldp_neighbors_total", "hostname", "vmx1")
+
+	for _, section := range []string{"system", "cpu", "memory", "interfaces", "interface_errors", "bgp", "ospf", "lldp"} {
+		if n := sectionErrors(t, m, section); n != 0 {
+			t.Errorf("section %s: %v errors", section, n)
+		}
+	}
+}
+
+func TestCollectSectionErrors(t *testing.T) {
+	replies := make(map[string]string, len(junosReplies))
+	for rpc, reply := range junosReplies {
+		replies[rpc] = reply
+	}
+	replies["get-bgp-summary-information"] = `<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>syntax error</error-message>
+</rpc-error>`
+	// An empty reply means OSPF is not configured, which is not an error.
+	replies["get-ospf-neighbor-information"] = ""
+
+	m, err := collect(t, &netconftest.Server{
+		Replies:  replies,
+		Unframed: map[string]bool{"get-lldp-neighbors-information": true},
+	})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	if n := sectionErrors(t, m, "bgp"); n != 1 {
+		t.Errorf("bgp errors = %v, want 1", n)
+	}
+	if _, ok := metricstest.Value(t, m, "netmetrics_bgp_neighbors_total", "hostname", "vmx1"); ok {
+		t.Error("BGP peer count exported despite the rpc-error")
+	}
+
+	if n := sectionErrors(t, m, "ospf"); n != 0 {
+		t.Errorf("ospf errors = %v, want 0", n)
+	}
+	expect(t, m, 0, "netmetrics_ospf_neighbors_total", "hostname", "vmx1")
+
+	if n := sectionErrors(t, m, "lldp"); n != 1 {
+		t.Errorf("lldp errors = %v, want 1", n)
+	}
+	if _, ok := metricstest.Value(t, m, "netmetrics_lldp_neighbors_total", "hostname", "vmx1"); ok {
+		t.Error("LLDP neighbor count exported despite the framing error")
+	}
+
+	// Sections before the failures are unaffected.
+	expect(t, m, 1, "netmetrics_interface_up", "interface", "ge-0/0/0")
+}
+
+func TestCollectUnreachable(t *testing.T) {
+	srv := &netconftest.Server{Replies: junosReplies}
+	device := srv.Start(t)
+	device.Hostname = "vmx1"
+	device.Password = "wrong"
+
+	m := metrics.NewSet()
+	if err := (JunosCollector{}).Collect(context.Background(), device, m); err == nil {
+		t.Fatal("Collect succeeded with bad credentials")
+	}
+	if n := sectionErrors(t, m, "system"); n != 1 {
+		t.Errorf("system errors = %v, want 1", n)
+	}
+}
+
+func TestParseSpeedMbps(t *testing.T) {
+	for speed, want := range map[string]float64{
+		"1000mbps":  1000,
+		"10Gbps":    10000,
+		"100G":      100000,
+		"Auto":      -1,
+		"Unlimited": -1,
+	} {
+		if got := parseSpeedMbps(speed); got != want {
+			t.Errorf("parseSpeedMbps(%q) = %v, want %v", speed, got, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package netconf is a minimal NETCONF client over SSH (RFC 6241/6242),
+// just enough for collectors to run read-only RPCs. It negotiates base:1.0
+// only, so every message is framed with the ]]>]]> end-of-message marker.
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+)
+
+// DefaultPort is the IANA NETCONF-over-SSH port.
+const DefaultPort = 830
+
+const (
+	endOfMessage = "]]>]]>"
+	baseNS       = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	hello        = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<hello xmlns="` + baseNS + `"><capabilities>` +
+		`<capability>urn:ietf:params:netconf:base:1.0</capability>` +
+		`</capabilities></hello>` + endOfMessage
+)
+
+// Session is an open NETCONF session. It is not safe for concurrent use.
+type Session struct {
+	client *ssh.Client
+	sess   *ssh.Session
+	w      io.Writer
+	r      *bufio.Reader
+	stop   func() bool
+	msgID  int
+}
+
+// Dial opens a NETCONF session to device on device.Port, or DefaultPort
+// when the inventory does not set one. The session is torn down if ctx is
+// cancelled, which unblocks any RPC in flight.
+func Dial(ctx context.Context, device inventory.Device) (*Session, error) {
+	port := device.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	addr := net.JoinHostPort(device.IP, strconv.Itoa(port))
+
+	client, err := collector.DialSSH(ctx, addr, &ssh.ClientConfig{
+		User:            device.Username,
+		Auth:            collector.PasswordAuth(device),
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("NETCONF connect failed: %w", err)
+	}
+
+	s := &Session{client: client}
+	s.stop = context.AfterFunc(ctx, func() { client.Close() })
+
+	if err := s.open(); err != nil {
+		s.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Session) open() error {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("NETCONF session failed: %w", err)
+	}
+	s.sess = sess
+
+	w, err := sess.StdinPipe()
+	if err != nil {
+		return err
+	}
+	r, err := sess.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	s.w, s.r = w, bufio.NewReader(r)
+
+	if err := sess.RequestSubsystem("netconf"); err != nil {
+		return fmt.Errorf("NETCONF subsystem refused: %w", err)
+	}
+	if _, err := io.WriteString(s.w, hello); err != nil {
+		return err
+	}
+	// The server's hello only lists capabilities, which we do not need.
+	if _, err := s.readMessage(); err != nil {
+		return fmt.Errorf("NETCONF hello failed: %w", err)
+	}
+	return nil
+}
+
+// Close ends the session and the SSH connection.
+func (s *Session) Close() error {
+	if s.stop != nil {
+		s.stop()
+	}
+	if s.sess != nil && s.w != nil {
+		fmt.Fprintf(s.w, `<rpc message-id="%d" xmlns="%s"><close-session/></rpc>%s`, s.msgID+1, baseNS, endOfMessage)
+		s.sess.Close()
+	}
+	return s.client.Close()
+}
+
+// ErrNoData is returned by Call when the reply carries neither data nor an
+// error, which is how some servers answer for a protocol that is not
+// configured.
+var ErrNoData = errors.New("NETCONF reply holds no data")
+
+// RPCError is an <rpc-error> returned by the server.
+type RPCError struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Message  string `xml:"error-message"`
+}
+
+func (e *RPCError) Error() string {
+	msg := strings.TrimSpace(e.Message)
+	if msg == "" {
+		msg = strings.TrimSpace(e.Tag)
+	}
+	return "NETCONF rpc-error: " + msg
+}
+
+// Call sends request, the XML body of an <rpc>, and decodes the first data
+// element of the reply into v. Warnings are ignored; the first rpc-error of
+// severity "error" is returned as an *RPCError.
+func (s *Session) Call(ctx context.Context, request string, v interface{}) error {
+	s.msgID++
+	if _, err := fmt.Fprintf(s.w, `<rpc message-id="%d" xmlns="%s">%s</rpc>%s`, s.msgID, baseNS, request, endOfMessage); err != nil {
+		return s.wrap(ctx, err)
+	}
+	reply, err := s.readMessage()
+	if err != nil {
+		return s.wrap(ctx, err)
+	}
+	return decodeReply(reply, v)
+}
+
+// Get runs a <get> with a subtree filter and decodes the <data> element of
+// the reply into v.
+func (s *Session) Get(ctx context.Context, filter string, v interface{}) error {
+	return s.Call(ctx, `<get><filter type="subtree">`+filter+`</filter></get>`, v)
+}
+
+func (s *Session) wrap(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readMessage reads up to the next end-of-message marker.
+func (s *Session) readMessage() ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		chunk, err := s.r.ReadBytes('>')
+		buf.Write(chunk)
+		if bytes.HasSuffix(buf.Bytes(), []byte(endOfMessage)) {
+			return buf.Bytes()[:buf.Len()-len(endOfMessage)], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeReply walks the children of <rpc-reply>: rpc-errors are checked,
+// <ok/> is skipped and the first other element is decoded into v.
+func decodeReply(reply []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(reply))
+	inReply := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return ErrNoData
+		}
+		if err != nil {
+			return fmt.Errorf("malformed NETCONF reply: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !inReply {
+				if t.Name.Local != "rpc-reply" {
+					return fmt.Errorf("unexpected NETCONF message <%s>", t.Name.Local)
+				}
+				inReply = true
+				continue
+			}
+			switch t.Name.Local {
+			case "rpc-error":
+				var rpcErr RPCError
+				if err := dec.DecodeElement(&rpcErr, &t); err != nil {
+					return err
+				}
+				if strings.TrimSpace(rpcErr.Severity) != "warning" {
+					return &rpcErr
+				}
+			case "ok":
+				dec.Skip()
+			default:
+				return dec.DecodeElement(v, &t)
+			}
+		case xml.EndElement:
+			return ErrNoData
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package netconf
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"netmetrics_exporter/internal/collector/netconf/netconftest"
+	"netmetrics_exporter/internal/inventory"
+)
+
+type softwareInformation struct {
+	Model   string `xml:"product-model"`
+	Version string `xml:"junos-version"`
+}
+
+const softwareReply = `<software-information>
+<product-model>vmx</product-model>
+<junos-version>23.2R1.14</junos-version>
+</software-information>`
+
+func dial(t *testing.T, device inventory.Device) *Session {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	s, err := Dial(ctx, device)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestCall(t *testing.T) {
+	srv := &netconftest.Server{Replies: map[string]string{
+		"get-software-information": softwareReply,
+	}}
+	s := dial(t, srv.Start(t))
+
+	var sw softwareInformation
+	if err := s.Call(context.Background(), "<get-software-information/>", &sw); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if sw.Model != "vmx" || sw.Version != "23.2R1.14" {
+		t.Errorf("decoded %+v", sw)
+	}
+
+	// The session stays usable for the next RPC.
+	if err := s.Call(context.Background(), "<get-software-information/>", &sw); err != nil {
+		t.Fatalf("second Call: %v", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	srv := &netconftest.Server{Replies: map[string]string{
+		"get": `<data><software-information><product-model>xrv9k</product-model></software-information></data>`,
+	}}
+	s := dial(t, srv.Start(t))
+
+	var data struct {
+		Model string `xml:"software-information>product-model"`
+	}
+	if err := s.Get(context.Background(), "<software-information/>", &data); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if data.Model != "xrv9k" {
+		t.Errorf("model = %q, want xrv9k", data.Model)
+	}
+	if got := srv.Requests(); len(got) != 1 || got[0] != "get" {
+		t.Errorf("requests = %v, want [get]", got)
+	}
+}
+
+func TestCallRPCError(t *testing.T) {
+	srv := &netconftest.Server{Replies: map[string]string{
+		"get-bgp-summary-information": `<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>
+BGP is not running
+</error-message>
+</rpc-error>`,
+	}}
+	s := dial(t, srv.Start(t))
+
+	var v struct{}
+	err := s.Call(context.Background(), "<get-bgp-summary-information/>", &v)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("err = %v, want *RPCError", err)
+	}
+	if got := rpcErr.Error(); got != "NETCONF rpc-error: BGP is not running" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestCallWarningIgnored(t *testing.T) {
+	srv := &netconftest.Server{Replies: map[string]string{
+		"get-software-information": `<rpc-error>
+<error-severity>warning</error-severity>
+<error-message>statistics are stale</error-message>
+</rpc-error>` + softwareReply,
+	}}
+	s := dial(t, srv.Start(t))
+
+	var sw softwareInformation
+	if err := s.Call(context.Background(), "<get-software-information/>", &sw); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if sw.Model != "vmx" {
+		t.Errorf("model = %q, want vmx", sw.Model)
+	}
+}
+
+func TestCallNoData(t *testing.T) {
+	srv := &netconftest.Server{Replies: map[string]string{
+		"get-ospf-neighbor-information": "",
+	}}
+	s := dial(t, srv.Start(t))
+
+	var v struct{}
+	if err := s.Call(context.Background(), "<get-ospf-neighbor-information/>", &v); !errors.Is(err, ErrNoData) {
+		t.Errorf("err = %v, want ErrNoData", err)
+	}
+}
+
+func TestCallFramingError(t *testing.T) {
+	srv := &netconftest.Server{
+		Replies:  map[string]string{"get-software-information": softwareReply},
+		Unframed: map[string]bool{"get-software-information": true},
+	}
+	s := dial(t, srv.Start(t))
+
+	var sw softwareInformation
+	err := s.Call(context.Background(), "<get-software-information/>", &sw)
+	if err == nil {
+		t.Fatal("Call succeeded on a reply without end-of-message marker")
+	}
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) || errors.Is(err, ErrNoData) {
+		t.Errorf("err = %v, want a transport error", err)
+	}
+}
+
+func TestDialRejectsBadCredentials(t *testing.T) {
+	srv := &netconftest.Server{}
+	device := srv.Start(t)
+	device.Password = "wrong"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Dial(ctx, device); err == nil || !strings.Contains(err.Error(), "NETCONF connect failed") {
+		t.Errorf("err = %v, want a connect failure", err)
+	}
+}
+
+func TestDecodeReplyMalformed(t *testing.T) {
+	var v struct{}
+	for _, reply := range []string{
+		`<hello xmlns="` + baseNS + `"/>`,
+		`<rpc-reply><data>`,
+	} {
+		if err := decodeReply([]byte(reply), &v); err == nil || errors.Is(err, ErrNoData) {
+			t.Errorf("decodeReply(%q) = %v, want a decode error", reply, err)
+		}
+	}
+}
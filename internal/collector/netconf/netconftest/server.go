@@ -0,0 +1,216 @@
+// Package netconftest runs an in-process NETCONF-over-SSH server that
+// answers RPCs from canned replies, for testing NETCONF collectors.
+package netconftest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"netmetrics_exporter/internal/inventory"
+)
+
+const (
+	// Username and Password are the only credentials the server accepts.
+	Username = "netconf"
+	Password = "netconf"
+
+	endOfMessage = "]]>]]>"
+	baseNS       = "urn:ietf:params:xml:ns:netconf:base:1.0"
+)
+
+// Server answers each RPC with the canned reply for its top element. RPCs
+// without a reply get an operation-not-supported rpc-error.
+type Server struct {
+	// Replies maps an RPC's top element, such as "get-interface-information"
+	// or "get", to the content of its <rpc-reply>.
+	Replies map[string]string
+
+	// Unframed lists RPCs whose reply is sent without the end-of-message
+	// marker, after which the server hangs up.
+	Unframed map[string]bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// Start serves on a loopback port until the test ends and returns a device
+// that points at it.
+func (s *Server) Start(t testing.TB) inventory.Device {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == Username && string(pass) == Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied for %s", c.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn, config)
+		}
+	}()
+
+	return inventory.Device{
+		Hostname: "netconf-test",
+		IP:       "127.0.0.1",
+		Port:     l.Addr().(*net.TCPAddr).Port,
+		Username: Username,
+		Password: Password,
+	}
+}
+
+// Requests returns the top element of every RPC received so far, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveSession(ch, chReqs)
+	}
+}
+
+func (s *Server) serveSession(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close()
+	for req := range reqs {
+		var subsystem struct{ Name string }
+		ok := req.Type == "subsystem" && ssh.Unmarshal(req.Payload, &subsystem) == nil && subsystem.Name == "netconf"
+		req.Reply(ok, nil)
+		if ok {
+			go ssh.DiscardRequests(reqs)
+			s.serveNETCONF(ch)
+			return
+		}
+	}
+}
+
+func (s *Server) serveNETCONF(ch ssh.Channel) {
+	io.WriteString(ch, `<?xml version="1.0" encoding="UTF-8"?>`+
+		`<hello xmlns="`+baseNS+`"><capabilities>`+
+		`<capability>urn:ietf:params:netconf:base:1.0</capability>`+
+		`</capabilities><session-id>1</session-id></hello>`+endOfMessage)
+
+	r := bufio.NewReader(ch)
+	if _, err := readMessage(r); err != nil { // the client's hello
+		return
+	}
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		id, op := parseRPC(msg)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, op)
+		s.mu.Unlock()
+
+		reply, ok := s.Replies[op]
+		switch {
+		case op == "close-session":
+			reply = "<ok/>"
+		case !ok:
+			reply = `<rpc-error><error-type>protocol</error-type>` +
+				`<error-tag>operation-not-supported</error-tag>` +
+				`<error-severity>error</error-severity>` +
+				`<error-message>` + op + ` is not supported</error-message></rpc-error>`
+		}
+
+		out := fmt.Sprintf(`<rpc-reply message-id="%s" xmlns="%s">%s</rpc-reply>`, id, baseNS, reply)
+		if s.Unframed[op] {
+			io.WriteString(ch, out)
+			return
+		}
+		io.WriteString(ch, out+endOfMessage)
+		if op == "close-session" {
+			return
+		}
+	}
+}
+
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		chunk, err := r.ReadBytes('>')
+		buf.Write(chunk)
+		if bytes.HasSuffix(buf.Bytes(), []byte(endOfMessage)) {
+			return buf.Bytes()[:buf.Len()-len(endOfMessage)], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRPC returns the message-id of an <rpc> and the name of its first
+// child element.
+func parseRPC(msg []byte) (id, op string) {
+	dec := xml.NewDecoder(bytes.NewReader(msg))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return id, op
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "rpc" {
+			return id, start.Name.Local
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "message-id" {
+				id = attr.Value
+			}
+		}
+	}
+}
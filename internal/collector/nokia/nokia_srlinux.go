@@ -2,47 +2,76 @@ package nokia
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"netmetrics_exporter/internal/collector"
 	"netmetrics_exporter/internal/inventory"
 	"netmetrics_exporter/internal/metrics"
 )
 
 type SRLinuxCollector struct{}
 
-func (c SRLinuxCollector) Collect(device inventory.Device) error {
-	if err := ensureHTTPAPIEnabled(device); err != nil {
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "srlinux",
+		Protocol: "jsonrpc",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapInterfaceCounters,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapISIS,
+			collector.CapLLDP,
+			collector.CapTransceivers,
+			collector.CapEnvironment,
+			collector.CapCPU,
+			collector.CapMemory,
+		},
+		New: func() collector.Collector { return SRLinuxCollector{} },
+	})
+}
+
+func (c SRLinuxCollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	if err := ensureHTTPAPIEnabled(ctx, device); err != nil {
 		return fmt.Errorf("JSON-RPC not enabled: %v", err)
 	}
 
 	// === 1. System Info ===
-	sysResp, err := runRPC(device, []string{"/system/information"})
+	start := time.Now()
+	sysResp, err := runRPC(ctx, device, []string{"/system/information"})
+	m.ObserveSection(device.Hostname, "system", start, err)
 	if err != nil {
 		return fmt.Errorf("Failed to fetch system info: %v", err)
 	}
 
 	version := safeStr(sysResp["version"])
 	description := safeStr(sysResp["description"])
-	metrics.DeviceInfo.WithLabelValues(device.Hostname, description, version).Set(1)
+	m.DeviceInfo.WithLabelValues(device.Hostname, description, version).Set(1)
 
 	if bootedAt, ok := sysResp["last-booted"].(string); ok {
 		parsed, err := time.Parse(time.RFC3339, bootedAt)
 		if err == nil {
 			uptime := time.Since(parsed).Seconds()
-			metrics.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime)
+			m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(uptime)
 		} else {
-			metrics.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(-1)
+			m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(-1)
 		}
 	}
 
 	// === 2. Interface Info ===
-	ifResp, err := runRPC(device, []string{"/interface"})
+	start = time.Now()
+	ifResp, err := runRPC(ctx, device, []string{"/interface"})
 	if err == nil {
 		ifList := extractNamespaceField(ifResp, "interface")
 		for _, raw := range ifList {
@@ -77,17 +106,20 @@ func (c SRLinuxCollector) Collect(device inventory.Device) error {
 				up = 1.0
 			}
 
-			metrics.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
-			metrics.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(speedMbps)
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(speedMbps)
 
 			if duplex != "unknown" {
-				metrics.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
+				m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, duplex).Set(1)
 			}
 		}
 	}
 
-	// === 3. Interface Errors ===
-	errResp, err := runRPC(device, []string{"/interface/statistics"})
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+
+	// === 3. Interface Errors and Traffic Counters ===
+	start = time.Now()
+	errResp, err := runRPC(ctx, device, []string{"/interface/statistics"})
 	if err == nil {
 		ifStats := extractNamespaceField(errResp, "statistics")
 		for _, raw := range ifStats {
@@ -95,42 +127,62 @@ func (c SRLinuxCollector) Collect(device inventory.Device) error {
 			name := safeStr(stat["interface"])
 			inErrors := toFloat(stat["in-errors"])
 			outErrors := toFloat(stat["out-errors"])
-			metrics.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(inErrors)
-			metrics.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(outErrors)
+			m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(inErrors)
+			m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(outErrors)
+
+			count := func(key string) float64 {
+				if v := toFloat(stat[key]); v > 0 {
+					return v
+				}
+				return 0
+			}
+			m.SetInterfaceCounters(device.Hostname, name, metrics.InterfaceCounters{
+				InOctets:            count("in-octets"),
+				OutOctets:           count("out-octets"),
+				InPackets:           count("in-packets"),
+				OutPackets:          count("out-packets"),
+				InUnicastPackets:    count("in-unicast-packets"),
+				OutUnicastPackets:   count("out-unicast-packets"),
+				InMulticastPackets:  count("in-multicast-packets"),
+				OutMulticastPackets: count("out-multicast-packets"),
+				InBroadcastPackets:  count("in-broadcast-packets"),
+				OutBroadcastPackets: count("out-broadcast-packets"),
+				InDiscards:          count("in-discarded-packets"),
+				OutDiscards:         count("out-discarded-packets"),
+			})
 		}
 	}
 
-	// === 4. BGP Peers ===
-	bgpResp, err := runRPC(device, []string{"/network-instance[name=default]/protocols/bgp/neighbor"})
-	peerCount := 0.0
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+	m.ObserveSection(device.Hostname, "interface_counters", start, err)
+
+	// === 4. BGP Sessions in every network instance ===
+	start = time.Now()
+	bgpResp, err := runRPC(ctx, device, []string{"/network-instance[name=*]/protocols/bgp/neighbor"})
 	if err == nil {
-		if neighbors, ok := bgpResp["neighbor"].([]interface{}); ok {
-			peerCount = float64(len(neighbors))
+		peers := bgpPeers(bgpResp)
+		for _, peer := range peers {
+			m.SetBGPPeer(device.Hostname, peer)
 		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(peers)))
 	}
-	metrics.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(peerCount)
+	m.ObserveSection(device.Hostname, "bgp", start, err)
 
-	// === 5. OSPF Neighbors ===
-	ospfNeighbors := 0.0
-	ospfResp, err := runRPC(device, []string{"/network-instance[name=default]/protocols/ospf/instance[name=ospf-default]/area"})
+	// === 5. OSPF Adjacencies in every network and OSPF instance ===
+	start = time.Now()
+	ospfResp, err := runRPC(ctx, device, []string{"/network-instance[name=*]/protocols/ospf/instance[name=*]/area"})
 	if err == nil {
-		if areas, ok := ospfResp["area"].([]interface{}); ok {
-			for _, a := range areas {
-				area := a.(map[string]interface{})
-				if ifs, ok := area["interface"].([]interface{}); ok {
-					for _, intf := range ifs {
-						if neighbors, ok := intf.(map[string]interface{})["neighbor"].([]interface{}); ok {
-							ospfNeighbors += float64(len(neighbors))
-						}
-					}
-				}
-			}
+		neighbors := ospfNeighbors(ospfResp)
+		for _, n := range neighbors {
+			m.SetOSPFNeighbor(device.Hostname, n)
 		}
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(neighbors)))
 	}
-	metrics.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(ospfNeighbors)
+	m.ObserveSection(device.Hostname, "ospf", start, err)
 
 	// === 6. LLDP Neighbors ===
-	lldpResp, err := runRPC(device, []string{"/system/lldp/interface"})
+	start = time.Now()
+	lldpResp, err := runRPC(ctx, device, []string{"/system/lldp/interface"})
 	count := 0.0
 	if err == nil {
 		ifaces, ok := lldpResp["interface"].([]interface{})
@@ -143,14 +195,151 @@ func (c SRLinuxCollector) Collect(device inventory.Device) error {
 			}
 		}
 	}
-	metrics.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(count)
+	m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(count)
+	m.ObserveSection(device.Hostname, "lldp", start, err)
 
-	return nil
+	// === 7. IS-IS Adjacencies and LSP Database ===
+	start = time.Now()
+	isisResp, err := runRPC(ctx, device, []string{"/network-instance[name=*]/protocols/isis/instance[name=*]/interface"})
+	if err == nil {
+		adjacencies := isisAdjacencies(isisResp)
+		for _, adj := range adjacencies {
+			m.SetISISAdjacency(device.Hostname, adj)
+		}
+		m.ISISAdjacencies.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(adjacencies)))
+
+		var dbResp map[string]interface{}
+		if dbResp, err = runRPC(ctx, device, []string{"/network-instance[name=*]/protocols/isis/instance[name=*]/level-database"}); err == nil {
+			walkList(dbResp, "default", "instance", func(_ string, inst map[string]interface{}) {
+				levels, _ := inst["level-database"].([]interface{})
+				for _, raw := range levels {
+					level, _ := raw.(map[string]interface{})
+					lsps, _ := level["lsp"].([]interface{})
+					m.ISISLSPDatabaseLSPs.WithLabelValues(device.Hostname, safeStr(inst["name"]), metrics.ISISLevel(numStr(level["level-number"]))).Set(float64(len(lsps)))
+				}
+			})
+		}
+	}
+	m.ObserveSection(device.Hostname, "isis", start, err)
+
+	// === 8. Optical Transceivers ===
+	start = time.Now()
+	xcvrResp, err := runRPC(ctx, device, []string{"/interface[name=*]/transceiver"})
+	if err == nil {
+		for _, raw := range extractNamespaceField(xcvrResp, "interface") {
+			intf, _ := raw.(map[string]interface{})
+			if x, ok := intf["transceiver"].(map[string]interface{}); ok {
+				m.SetTransceiver(device.Hostname, transceiver(safeStr(intf["name"]), x))
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "transceivers", start, err)
+
+	// === 9. Environment: cards, fans and power supplies ===
+	start = time.Now()
+	platResp, err := runRPC(ctx, device, []string{"/platform"})
+	if err == nil {
+		// Every component reports its own temperature; cards also report
+		// their operational state.
+		for _, kind := range []string{"control", "linecard", "fabric", "power-supply", "fan-tray"} {
+			for _, raw := range extractNamespaceField(platResp, kind) {
+				comp, _ := raw.(map[string]interface{})
+				id := numStr(firstOf(comp, "slot", "id"))
+				name := kind + " " + id
+				oper := safeStr(comp["oper-state"])
+				if oper == "empty" {
+					continue
+				}
+
+				if temp, ok := comp["temperature"].(map[string]interface{}); ok {
+					if cur := reading(temp["instant"]); !math.IsNaN(cur) {
+						m.TemperatureCelsius.WithLabelValues(device.Hostname, name).Set(cur)
+					}
+				}
+
+				up := 0.0
+				if oper == "up" {
+					up = 1
+				}
+				switch kind {
+				case "fan-tray":
+					m.FanOK.WithLabelValues(device.Hostname, id).Set(up)
+					if speed := reading(comp["speed"]); !math.IsNaN(speed) {
+						m.FanSpeedPercent.WithLabelValues(device.Hostname, id).Set(speed)
+					}
+				case "power-supply":
+					m.PSUOK.WithLabelValues(device.Hostname, id).Set(up)
+					if input, ok := comp["input"].(map[string]interface{}); ok {
+						if power := reading(input["power"]); !math.IsNaN(power) {
+							m.PSUInputPowerWatts.WithLabelValues(device.Hostname, id).Set(power)
+						}
+					}
+					if output, ok := comp["output"].(map[string]interface{}); ok {
+						if power := reading(output["power"]); !math.IsNaN(power) {
+							m.PSUOutputPowerWatts.WithLabelValues(device.Hostname, id).Set(power)
+						}
+					}
+				default:
+					m.ModuleUp.WithLabelValues(device.Hostname, name).Set(up)
+				}
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "environment", start, err)
+
+	// === 10. CPU and Memory of the active control module ===
+	start = time.Now()
+	ctlResp, err := runRPC(ctx, device, []string{"/platform/control"})
+	if err == nil {
+		var ctl map[string]interface{}
+		for _, raw := range extractNamespaceField(ctlResp, "control") {
+			c, _ := raw.(map[string]interface{})
+			if ctl == nil || safeStr(c["role"]) == "active" {
+				ctl = c
+			}
+		}
+
+		// CPU "all" is the average over every core
+		cpus, _ := ctl["cpu"].([]interface{})
+		for _, raw := range cpus {
+			cpu, _ := raw.(map[string]interface{})
+			total, _ := cpu["total"].(map[string]interface{})
+			index := numStr(cpu["index"])
+			if index != "all" {
+				if v := reading(total["instant"]); !math.IsNaN(v) {
+					m.CPUCoreUsage.WithLabelValues(device.Hostname, device.Vendor, index).Set(v)
+				}
+				continue
+			}
+			if v := reading(total["instant"]); !math.IsNaN(v) {
+				m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(v)
+			}
+			for window, key := range map[string]string{"1m": "average-1", "5m": "average-5", "15m": "average-15"} {
+				if v := reading(total[key]); !math.IsNaN(v) {
+					m.CPUUsageAverage.WithLabelValues(device.Hostname, device.Vendor, window).Set(v)
+				}
+			}
+		}
+
+		// Memory is in bytes; reserved is what is in use
+		if mem, ok := ctl["memory"].(map[string]interface{}); ok {
+			total, used := reading(mem["physical"]), reading(mem["reserved"])
+			if !math.IsNaN(total) && !math.IsNaN(used) {
+				m.SetMemory(device.Hostname, device.Vendor, total, used)
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+	m.ObserveSection(device.Hostname, "memory", start, err)
+
+	// Sections swallow their own errors; make sure a cancelled or expired
+	// context still fails the poll.
+	return ctx.Err()
 }
 
 // === Helpers ===
 
-func ensureHTTPAPIEnabled(device inventory.Device) error {
+func ensureHTTPAPIEnabled(ctx context.Context, device inventory.Device) error {
 	payload := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"method":  "patch",
@@ -168,17 +357,15 @@ func ensureHTTPAPIEnabled(device inventory.Device) error {
 	}
 	data, _ := json.Marshal(payload)
 
-	url := fmt.Sprintf("http://%s/jsonrpc", device.IP)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	url := collector.BaseURL(device, "http") + "/jsonrpc"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
 	req.SetBasicAuth(device.Username, device.Password)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-	resp, err := client.Do(req)
+	resp, err := collector.HTTPClient(device).Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to enable JSON-RPC: %v", err)
 	}
@@ -191,7 +378,7 @@ func ensureHTTPAPIEnabled(device inventory.Device) error {
 	return nil
 }
 
-func runRPC(device inventory.Device, paths []string) (map[string]interface{}, error) {
+func runRPC(ctx context.Context, device inventory.Device, paths []string) (map[string]interface{}, error) {
 	var commands []map[string]interface{}
 	for _, path := range paths {
 		commands = append(commands, map[string]interface{}{
@@ -211,21 +398,24 @@ func runRPC(device inventory.Device, paths []string) (map[string]interface{}, er
 	}
 	data, _ := json.Marshal(payload)
 
-	url := fmt.Sprintf("http://%s/jsonrpc", device.IP)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	url := collector.BaseURL(device, "http") + "/jsonrpc"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
 	req.SetBasicAuth(device.Username, device.Password)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
-	}
-	resp, err := client.Do(req)
+	resp, err := collector.HTTPClient(device).Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	debugLog("📥 SRL Raw Body from %s: %s\n", device.Hostname, string(body))
 
 	var jsonResp map[string]interface{}
@@ -254,13 +444,256 @@ func safeStr(v interface{}) string {
 	return "unknown"
 }
 
+// toFloat reads a number, including the 64-bit counters that SR Linux
+// sends as strings, or returns -1.
 func toFloat(v interface{}) float64 {
-	if f, ok := v.(float64); ok {
-		return f
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			return f
+		}
 	}
 	return -1
 }
 
+// bgpPeers turns every BGP neighbor entry in resp into a session.
+func bgpPeers(resp map[string]interface{}) []metrics.BGPPeer {
+	var peers []metrics.BGPPeer
+	walkList(resp, "default", "neighbor", func(instance string, n map[string]interface{}) {
+		peer := metrics.BGPPeer{
+			VRF:                instance,
+			Address:            safeStr(n["peer-address"]),
+			RemoteAS:           numStr(n["peer-as"]),
+			State:              safeStr(n["session-state"]),
+			EstablishedSeconds: -1,
+			Flaps:              toFloat(n["established-transitions"]),
+		}
+		if last, ok := n["last-established"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, last); err == nil {
+				peer.EstablishedSeconds = time.Since(t).Seconds()
+			}
+		}
+
+		// Recent releases list the families under afi-safi, older ones
+		// have a container per family.
+		families := map[string]map[string]interface{}{}
+		if list, ok := n["afi-safi"].([]interface{}); ok {
+			for _, raw := range list {
+				if f, ok := raw.(map[string]interface{}); ok {
+					families[localName(safeStr(f["afi-safi-name"]))] = f
+				}
+			}
+		}
+		for _, name := range []string{"ipv4-unicast", "ipv6-unicast", "evpn"} {
+			if f, ok := n[name].(map[string]interface{}); ok {
+				families[name] = f
+			}
+		}
+		for name, f := range families {
+			received := toFloat(f["received-routes"])
+			accepted := -1.0
+			if rejected := toFloat(f["rejected-routes"]); received >= 0 && rejected >= 0 {
+				accepted = received - rejected
+			}
+			peer.Families = append(peer.Families, metrics.BGPFamily{
+				AFISAFI:    name,
+				Received:   received,
+				Accepted:   accepted,
+				Advertised: toFloat(f["sent-routes"]),
+			})
+		}
+		peers = append(peers, peer)
+	})
+	return peers
+}
+
+// ospfNeighbors turns every OSPF neighbor entry in resp into an adjacency.
+func ospfNeighbors(resp map[string]interface{}) []metrics.OSPFNeighbor {
+	var neighbors []metrics.OSPFNeighbor
+	walkList(resp, "default", "instance", func(vrf string, inst map[string]interface{}) {
+		areas, _ := inst["area"].([]interface{})
+		for _, raw := range areas {
+			area, _ := raw.(map[string]interface{})
+			ifaces, _ := area["interface"].([]interface{})
+			for _, raw := range ifaces {
+				iface, _ := raw.(map[string]interface{})
+				list, _ := iface["neighbor"].([]interface{})
+				for _, raw := range list {
+					nbr, _ := raw.(map[string]interface{})
+					n := metrics.OSPFNeighbor{
+						VRF:           vrf,
+						Instance:      safeStr(inst["name"]),
+						Area:          safeStr(area["area-id"]),
+						Interface:     safeStr(iface["interface-name"]),
+						RouterID:      safeStr(nbr["router-id"]),
+						Address:       safeStr(firstOf(nbr, "neighbor-address", "ip-address")),
+						State:         safeStr(nbr["adjacency-state"]),
+						UptimeSeconds: -1,
+					}
+					if since, ok := firstOf(nbr, "last-established-time", "last-transition-time").(string); ok {
+						if t, err := time.Parse(time.RFC3339, since); err == nil {
+							n.UptimeSeconds = time.Since(t).Seconds()
+						}
+					}
+					neighbors = append(neighbors, n)
+				}
+			}
+		}
+	})
+	return neighbors
+}
+
+// isisAdjacencies turns every IS-IS adjacency entry in resp into an
+// adjacency.
+func isisAdjacencies(resp map[string]interface{}) []metrics.ISISAdjacency {
+	var adjacencies []metrics.ISISAdjacency
+	walkList(resp, "default", "instance", func(_ string, inst map[string]interface{}) {
+		ifaces, _ := inst["interface"].([]interface{})
+		for _, raw := range ifaces {
+			iface, _ := raw.(map[string]interface{})
+			list, _ := iface["adjacency"].([]interface{})
+			for _, raw := range list {
+				a, _ := raw.(map[string]interface{})
+				adj := metrics.ISISAdjacency{
+					Instance:        safeStr(inst["name"]),
+					SystemID:        safeStr(a["neighbor-system-id"]),
+					Interface:       safeStr(iface["interface-name"]),
+					Level:           safeStr(a["adjacency-level"]),
+					State:           safeStr(a["adjacency-state"]),
+					HoldTimeSeconds: toFloat(a["remaining-holdtime"]),
+					UptimeSeconds:   -1,
+				}
+				if since, ok := a["last-up-time"].(string); ok {
+					if t, err := time.Parse(time.RFC3339, since); err == nil {
+						adj.UptimeSeconds = time.Since(t).Seconds()
+					}
+				}
+				adjacencies = append(adjacencies, adj)
+			}
+		}
+	})
+	return adjacencies
+}
+
+// transceiver reads the state of one interface's transceiver. Every sensor
+// container holds its latest value and thresholds; lanes are channels.
+func transceiver(iface string, x map[string]interface{}) metrics.Transceiver {
+	t := metrics.Transceiver{Interface: iface}
+	t.Manufacturer, _ = x["vendor"].(string)
+	t.PartNumber, _ = x["vendor-part-number"].(string)
+	t.Serial, _ = x["serial-number"].(string)
+
+	sensor := func(name string, v interface{}, thresholds bool) float64 {
+		c, _ := v.(map[string]interface{})
+		if thresholds {
+			for key, level := range map[string]string{
+				"high-alarm-threshold":   "high_alarm",
+				"high-warning-threshold": "high_warning",
+				"low-warning-threshold":  "low_warning",
+				"low-alarm-threshold":    "low_alarm",
+			} {
+				if th := reading(c[key]); !math.IsNaN(th) {
+					t.Thresholds = append(t.Thresholds, metrics.TransceiverThreshold{Sensor: name, Level: level, Value: th})
+				}
+			}
+		}
+		return reading(c["latest-value"])
+	}
+
+	t.TemperatureCelsius = sensor("temperature", x["temperature"], true)
+	t.VoltageVolts = sensor("voltage", x["voltage"], true)
+	channels, _ := x["channel"].([]interface{})
+	for i, raw := range channels {
+		ch, _ := raw.(map[string]interface{})
+		// Every lane shares the same thresholds
+		first := i == 0
+		t.Lanes = append(t.Lanes, metrics.TransceiverLane{
+			Lane:             numStr(ch["index"]),
+			RxPowerDBm:       sensor("rx_power", ch["input-power"], first),
+			TxPowerDBm:       sensor("tx_power", ch["output-power"], first),
+			BiasMilliamperes: sensor("bias_current", ch["laser-bias-current"], first),
+		})
+	}
+	return t
+}
+
+// reading is toFloat for sensors, whose readings can be negative: a missing
+// reading is NaN.
+func reading(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+// firstOf returns the first of keys present in entry, for leaves that were
+// renamed between releases.
+func firstOf(entry map[string]interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		if v, ok := entry[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// walkList calls fn for every entry of the lists named list below v, along
+// with the network instance it sits in. Replies to wildcard paths start at
+// the network-instance list; others start below it, in instance.
+func walkList(v interface{}, instance, list string, fn func(instance string, entry map[string]interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			switch localName(k) {
+			case "network-instance":
+				entries, _ := child.([]interface{})
+				for _, raw := range entries {
+					if ni, ok := raw.(map[string]interface{}); ok {
+						walkList(ni, safeStr(ni["name"]), list, fn)
+					}
+				}
+			case list:
+				entries, _ := child.([]interface{})
+				for _, raw := range entries {
+					if entry, ok := raw.(map[string]interface{}); ok {
+						fn(instance, entry)
+					}
+				}
+			default:
+				walkList(child, instance, list, fn)
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			walkList(child, instance, list, fn)
+		}
+	}
+}
+
+// localName strips the module prefix SR Linux puts on some names, as in
+// "srl_nokia-bgp:bgp".
+func localName(name string) string {
+	return name[strings.LastIndex(name, ":")+1:]
+}
+
+// numStr formats a number that may arrive as a JSON number or a string.
+func numStr(v interface{}) string {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case string:
+		return n
+	}
+	return "unknown"
+}
+
 func extractNamespaceField(resp map[string]interface{}, field string) []interface{} {
 	if val, ok := resp[field]; ok {
 		if items, ok := val.([]interface{}); ok {
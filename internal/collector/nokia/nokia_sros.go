@@ -0,0 +1,289 @@
+package nokia
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/collector/netconf"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// SROSCollector polls classic SR OS routers (7750, 7250, ...) over NETCONF
+// with the nokia-state model. MD-CLI mode and "system management-interface
+// netconf admin-state enable" are required.
+type SROSCollector struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Vendor:   "sros",
+		Protocol: "netconf",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapISIS,
+			collector.CapLLDP,
+			collector.CapCPU,
+			collector.CapEnvironment,
+		},
+		New: func() collector.Collector { return SROSCollector{} },
+	})
+}
+
+// Subtree filters, one per section, all in the nokia-state namespace.
+const (
+	srosState = `<state xmlns="urn:nokia.com:sros:ns:yang:sr:state">`
+
+	srosSystemFilter = srosState + `<system><platform/><up-time/><version><version-number/></version></system></state>`
+	srosPortFilter   = srosState + `<port><port-id/><oper-state/>` +
+		`<ethernet><oper-speed/><oper-duplex/></ethernet>` +
+		`<statistics><in-errors/><out-errors/></statistics></port></state>`
+	srosBGPFilter = srosState + `<router><router-name/><bgp><neighbor><ip-address/></neighbor></bgp></router>` +
+		`<service><vprn><service-name/><bgp><neighbor><ip-address/></neighbor></bgp></vprn></service></state>`
+	srosOSPFFilter = srosState + `<router><router-name/><ospf><area><interface><neighbor/></interface></area></ospf></router></state>`
+	srosISISFilter = srosState + `<router><router-name/><isis><interface><adjacency/></interface></isis></router></state>`
+	srosLLDPFilter = srosState + `<port><port-id/><ethernet><lldp><dest-mac><remote-system/></dest-mac></lldp></ethernet></port></state>`
+	srosCPUFilter  = srosState + `<system><cpu><sample-period/><summary><usage><cpu-usage/></usage></summary></cpu></system></state>`
+	srosCardFilter = srosState + `<card><slot-number/><hardware-data><oper-state/></hardware-data>` +
+		`<mda><mda-slot/><hardware-data><oper-state/></hardware-data></mda></card></state>`
+)
+
+// Every struct below decodes the <data> element of a <get> reply.
+
+type srosSystem struct {
+	Platform string  `xml:"state>system>platform"`
+	UpTime   float64 `xml:"state>system>up-time"` // hundredths of a second
+	Version  string  `xml:"state>system>version>version-number"`
+}
+
+type srosPorts struct {
+	Ports []struct {
+		ID        string  `xml:"port-id"`
+		OperState string  `xml:"oper-state"`
+		Speed     float64 `xml:"ethernet>oper-speed"` // Mbps
+		Duplex    string  `xml:"ethernet>oper-duplex"`
+		InErrors  float64 `xml:"statistics>in-errors"`
+		OutErrors float64 `xml:"statistics>out-errors"`
+	} `xml:"state>port"`
+}
+
+type srosBGP struct {
+	Routers []struct {
+		Neighbors []struct{} `xml:"bgp>neighbor"`
+	} `xml:"state>router"`
+	VPRNs []struct {
+		Neighbors []struct{} `xml:"bgp>neighbor"`
+	} `xml:"state>service>vprn"`
+}
+
+type srosOSPF struct {
+	Routers []struct {
+		Areas []struct {
+			Interfaces []struct {
+				Neighbors []struct{} `xml:"neighbor"`
+			} `xml:"interface"`
+		} `xml:"ospf>area"`
+	} `xml:"state>router"`
+}
+
+type srosISIS struct {
+	Routers []struct {
+		Interfaces []struct {
+			Adjacencies []struct{} `xml:"adjacency"`
+		} `xml:"isis>interface"`
+	} `xml:"state>router"`
+}
+
+type srosLLDP struct {
+	Ports []struct {
+		DestMACs []struct {
+			Remotes []struct{} `xml:"remote-system"`
+		} `xml:"ethernet>lldp>dest-mac"`
+	} `xml:"state>port"`
+}
+
+type srosCPU struct {
+	Samples []struct {
+		Period string  `xml:"sample-period"`
+		Usage  float64 `xml:"summary>usage>cpu-usage"`
+	} `xml:"state>system>cpu"`
+}
+
+type srosCards struct {
+	Cards []struct {
+		Slot      string `xml:"slot-number"`
+		OperState string `xml:"hardware-data>oper-state"`
+		MDAs      []struct {
+			Slot      string `xml:"mda-slot"`
+			OperState string `xml:"hardware-data>oper-state"`
+		} `xml:"mda"`
+	} `xml:"state>card"`
+}
+
+func (c SROSCollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// One NETCONF session carries every request. Opening it and reading
+	// the system state double as the reachability check.
+	start := time.Now()
+	session, err := netconf.Dial(ctx, device)
+	if err != nil {
+		m.ObserveSection(device.Hostname, "system", start, err)
+		return err
+	}
+	defer session.Close()
+
+	// 1) Platform, version & uptime
+	var sys srosSystem
+	err = srosGet(ctx, session, device, srosSystemFilter, &sys)
+	m.ObserveSection(device.Hostname, "system", start, err)
+	if err != nil {
+		return err
+	}
+	m.DeviceInfo.WithLabelValues(device.Hostname, strings.TrimSpace(sys.Platform), strings.TrimSpace(sys.Version)).Set(1)
+	m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(sys.UpTime / 100)
+
+	// 2) Ports and their error counters
+	start = time.Now()
+	var ports srosPorts
+	err = srosGet(ctx, session, device, srosPortFilter, &ports)
+	if err == nil {
+		for _, port := range ports.Ports {
+			up := 0.0
+			if port.OperState == "up" {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, port.ID, device.Vendor).Set(up)
+			m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, port.ID, device.Vendor).Set(port.Speed)
+
+			duplex := port.Duplex
+			if duplex == "" {
+				duplex = "unknown"
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, port.ID, device.Vendor, duplex).Set(1)
+
+			m.InterfaceInputErrors.WithLabelValues(device.Hostname, port.ID).Set(port.InErrors)
+			m.InterfaceOutputErrors.WithLabelValues(device.Hostname, port.ID).Set(port.OutErrors)
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	// 3) BGP neighbors in every router instance and VPRN
+	start = time.Now()
+	var bgp srosBGP
+	err = srosGet(ctx, session, device, srosBGPFilter, &bgp)
+	if err == nil {
+		peers := 0
+		for _, r := range bgp.Routers {
+			peers += len(r.Neighbors)
+		}
+		for _, v := range bgp.VPRNs {
+			peers += len(v.Neighbors)
+		}
+		m.BGPPeers.WithLabelValues(device.Hostname, device.Vendor).Set(float64(peers))
+	}
+	m.ObserveSection(device.Hostname, "bgp", start, err)
+
+	// 4) OSPF neighbors in every router instance
+	start = time.Now()
+	var ospf srosOSPF
+	err = srosGet(ctx, session, device, srosOSPFFilter, &ospf)
+	if err == nil {
+		neighbors := 0
+		for _, r := range ospf.Routers {
+			for _, area := range r.Areas {
+				for _, iface := range area.Interfaces {
+					neighbors += len(iface.Neighbors)
+				}
+			}
+		}
+		m.OSPFNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+	}
+	m.ObserveSection(device.Hostname, "ospf", start, err)
+
+	// 5) IS-IS adjacencies in every router instance
+	start = time.Now()
+	var isis srosISIS
+	err = srosGet(ctx, session, device, srosISISFilter, &isis)
+	if err == nil {
+		adjacencies := 0
+		for _, r := range isis.Routers {
+			for _, iface := range r.Interfaces {
+				adjacencies += len(iface.Adjacencies)
+			}
+		}
+		m.ISISAdjacencies.WithLabelValues(device.Hostname, device.Vendor).Set(float64(adjacencies))
+	}
+	m.ObserveSection(device.Hostname, "isis", start, err)
+
+	// 6) LLDP remote systems on every port
+	start = time.Now()
+	var lldp srosLLDP
+	err = srosGet(ctx, session, device, srosLLDPFilter, &lldp)
+	if err == nil {
+		neighbors := 0
+		for _, port := range lldp.Ports {
+			for _, dest := range port.DestMACs {
+				neighbors += len(dest.Remotes)
+			}
+		}
+		m.LLDPNeighbors.WithLabelValues(device.Hostname, device.Vendor).Set(float64(neighbors))
+	}
+	m.ObserveSection(device.Hostname, "lldp", start, err)
+
+	// 7) CPU, averaged over the 60 second sample period when available
+	start = time.Now()
+	var cpu srosCPU
+	err = srosGet(ctx, session, device, srosCPUFilter, &cpu)
+	if err == nil && len(cpu.Samples) > 0 {
+		usage := cpu.Samples[0].Usage
+		for _, s := range cpu.Samples {
+			if strings.TrimSpace(s.Period) == "60" {
+				usage = s.Usage
+			}
+		}
+		m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(usage)
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+
+	// 8) Card and MDA operational state
+	start = time.Now()
+	var cards srosCards
+	err = srosGet(ctx, session, device, srosCardFilter, &cards)
+	if err == nil {
+		for _, card := range cards.Cards {
+			m.ModuleUp.WithLabelValues(device.Hostname, "card "+card.Slot).Set(srosInService(card.OperState))
+			for _, mda := range card.MDAs {
+				m.ModuleUp.WithLabelValues(device.Hostname, "mda "+card.Slot+"/"+mda.Slot).Set(srosInService(mda.OperState))
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "environment", start, err)
+
+	return ctx.Err()
+}
+
+// srosGet runs one subtree <get> and, with NETMETRICS_DEBUG=1, dumps the
+// decoded reply.
+func srosGet(ctx context.Context, session *netconf.Session, device inventory.Device, filter string, v interface{}) error {
+	if err := session.Get(ctx, filter, v); err != nil {
+		return err
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("DEBUG NETCONF RESULT from %s: %+v\n", device.Hostname, v)
+	}
+	return nil
+}
+
+func srosInService(state string) float64 {
+	if strings.TrimSpace(state) == "in-service" {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"netmetrics_exporter/internal/inventory"
+)
+
+// Sections a collector can report, listed as its capabilities. They match
+// the section label of the health metrics.
+const (
+	CapSystem            = "system"
+	CapInterfaces        = "interfaces"
+	CapInterfaceErrors   = "interface_errors"
+	CapInterfaceCounters = "interface_counters"
+	CapBGP               = "bgp"
+	CapOSPF              = "ospf"
+	CapISIS              = "isis"
+	CapLLDP              = "lldp"
+	CapTransceivers      = "transceivers"
+	CapCPU               = "cpu"
+	CapMemory            = "memory"
+	CapEnvironment       = "environment"
+)
+
+// Factory creates a collector. It is called at most once per registration;
+// the collector it returns is shared by every device it polls.
+type Factory func() Collector
+
+// Registration describes a collector to the registry.
+type Registration struct {
+	// Vendor is the normalized vendor name, as produced by
+	// inventory.NormalizeVendor. An empty Vendor registers a generic
+	// collector (gNMI, SNMP, ...) that serves any vendor, but only when the
+	// inventory asks for its protocol.
+	Vendor string
+	// Protocol is the management protocol the collector speaks. The first
+	// protocol registered for a vendor is its default.
+	Protocol     string
+	Capabilities []string
+	New          Factory
+}
+
+var registry = struct {
+	sync.Mutex
+	regs      []Registration
+	instances map[string]Collector
+}{instances: make(map[string]Collector)}
+
+// Register makes a collector available. Vendor packages call it from init,
+// so importing a package is all it takes to enable it. Registering the same
+// vendor and protocol twice panics.
+func Register(r Registration) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if r.Protocol == "" || r.New == nil {
+		panic("collector: Register needs a protocol and a factory")
+	}
+	for _, existing := range registry.regs {
+		if existing.Vendor == r.Vendor && existing.Protocol == r.Protocol {
+			panic(fmt.Sprintf("collector: %s/%s registered twice", r.Vendor, r.Protocol))
+		}
+	}
+	registry.regs = append(registry.regs, r)
+}
+
+// Registrations returns every registered collector, sorted by vendor, with
+// each vendor's default protocol first and the generic collectors last.
+func Registrations() []Registration {
+	registry.Lock()
+	defer registry.Unlock()
+
+	out := append([]Registration(nil), registry.regs...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].Vendor == "") != (out[j].Vendor == "") {
+			return out[j].Vendor == ""
+		}
+		return out[i].Vendor < out[j].Vendor
+	})
+	return out
+}
+
+// Lookup finds the registration for vendor and protocol. An empty protocol
+// selects the vendor's default. A vendor-specific collector wins over a
+// generic one for the same protocol.
+func Lookup(vendor, protocol string) (Registration, error) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	found := false
+	for _, r := range registry.regs {
+		if r.Vendor != vendor || vendor == "" {
+			continue
+		}
+		found = true
+		if protocol == "" || r.Protocol == protocol {
+			return r, nil
+		}
+	}
+	if protocol != "" {
+		for _, r := range registry.regs {
+			if r.Vendor == "" && r.Protocol == protocol {
+				return r, nil
+			}
+		}
+	}
+	if !found {
+		return Registration{}, fmt.Errorf("no collector for vendor %q", vendor)
+	}
+	return Registration{}, fmt.Errorf("no %s collector for protocol %q", vendor, protocol)
+}
+
+// For returns the collector that polls device, creating it on first use.
+func For(device inventory.Device) (Collector, error) {
+	r, err := Lookup(device.Vendor, device.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	key := r.Vendor + "/" + r.Protocol
+	c, ok := registry.instances[key]
+	if !ok {
+		c = r.New()
+		registry.instances[key] = c
+	}
+	return c, nil
+}
@@ -0,0 +1,448 @@
+// Package snmp is a vendor-neutral fallback collector for devices without a
+// modern management API. It walks the standard IF-MIB, EtherLike-MIB,
+// BGP4-MIB, OSPF-MIB, LLDP-MIB and HOST-RESOURCES-MIB tables.
+//
+// Devices opt in with netmetrics_protocol: snmp. Credentials come from
+// these inventory variables:
+//
+//	netmetrics_snmp_version         2c (default) or 3
+//	netmetrics_snmp_port            UDP port, default 161
+//	netmetrics_snmp_community       v2c community, default public
+//	netmetrics_snmp_username        v3 user name
+//	netmetrics_snmp_security_level  noAuthNoPriv, authNoPriv or authPriv; default from the passwords set
+//	netmetrics_snmp_auth_protocol   md5, sha (default), sha224, sha256, sha384 or sha512
+//	netmetrics_snmp_auth_password   v3 authentication passphrase
+//	netmetrics_snmp_priv_protocol   des, aes (default), aes192, aes256, aes192c or aes256c
+//	netmetrics_snmp_priv_password   v3 privacy passphrase
+//	netmetrics_snmp_context         v3 context name
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+)
+
+// DefaultPort is the standard SNMP agent port.
+const DefaultPort = 161
+
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+
+	// IF-MIB
+	oidIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	oidIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	oidIfInErrors    = "1.3.6.1.2.1.2.2.1.14"
+	oidIfOutErrors   = "1.3.6.1.2.1.2.2.1.20"
+	oidIfName        = "1.3.6.1.2.1.31.1.1.1.1"
+	oidIfHCInOctets  = "1.3.6.1.2.1.31.1.1.1.6"
+	oidIfHCOutOctets = "1.3.6.1.2.1.31.1.1.1.10"
+	oidIfHighSpeed   = "1.3.6.1.2.1.31.1.1.1.15"
+
+	// EtherLike-MIB dot3StatsDuplexStatus, indexed by ifIndex
+	oidDot3Duplex = "1.3.6.1.2.1.10.7.2.1.19"
+
+	oidBGPPeerState   = "1.3.6.1.2.1.15.3.1.2"     // BGP4-MIB
+	oidOSPFNbrState   = "1.3.6.1.2.1.14.10.1.6"    // OSPF-MIB
+	oidLLDPRemChassis = "1.0.8802.1.1.2.1.4.1.1.5" // LLDP-MIB lldpRemChassisId
+
+	// HOST-RESOURCES-MIB
+	oidHrProcessorLoad = "1.3.6.1.2.1.25.3.3.1.2"
+	oidHrStorageType   = "1.3.6.1.2.1.25.2.3.1.2"
+	oidHrStorageUnits  = "1.3.6.1.2.1.25.2.3.1.4"
+	oidHrStorageSize   = "1.3.6.1.2.1.25.2.3.1.5"
+	oidHrStorageUsed   = "1.3.6.1.2.1.25.2.3.1.6"
+	oidHrStorageRAM    = "1.3.6.1.2.1.25.2.1.2"
+)
+
+type SNMPCollector struct{}
+
+func init() {
+	collector.Register(collector.Registration{
+		Protocol: "snmp",
+		Capabilities: []string{
+			collector.CapSystem,
+			collector.CapInterfaces,
+			collector.CapInterfaceErrors,
+			collector.CapInterfaceCounters,
+			collector.CapBGP,
+			collector.CapOSPF,
+			collector.CapLLDP,
+			collector.CapCPU,
+			collector.CapMemory,
+		},
+		New: func() collector.Collector { return SNMPCollector{} },
+	})
+}
+
+func (c SNMPCollector) Collect(ctx context.Context, device inventory.Device, m *metrics.Set) error {
+	// 1) System group; SNMP is connectionless, so this is the reachability check
+	start := time.Now()
+	client, err := connect(ctx, device)
+	if err != nil {
+		m.ObserveSection(device.Hostname, "system", start, err)
+		return err
+	}
+	defer client.Conn.Close()
+
+	sys, err := client.Get([]string{oidSysDescr, oidSysObjectID, oidSysUpTime})
+	m.ObserveSection(device.Hostname, "system", start, err)
+	if err != nil {
+		return fmt.Errorf("SNMP get system: %w", err)
+	}
+	var descr, objectID string
+	for _, v := range sys.Variables {
+		switch strings.TrimPrefix(v.Name, ".") {
+		case oidSysDescr:
+			descr = firstLine(str(v))
+		case oidSysObjectID:
+			objectID = strings.TrimPrefix(str(v), ".")
+		case oidSysUpTime:
+			m.DeviceUptimeSeconds.WithLabelValues(device.Hostname).Set(num(v) / 100) // TimeTicks
+		}
+	}
+	m.DeviceInfo.WithLabelValues(device.Hostname, objectID, descr).Set(1)
+
+	// 2) Interfaces; the name table labels every per-interface section
+	// below, so when it cannot be walked each of them records the failure
+	start = time.Now()
+	ifNames, ifErr := walk(client, device, oidIfName)
+	if ifErr == nil && len(ifNames) == 0 {
+		ifNames, ifErr = walk(client, device, oidIfDescr) // no IF-MIB ifXTable
+	}
+	var oper, speed, duplex map[string]gosnmp.SnmpPDU
+	err = ifErr
+	if err == nil {
+		oper, err = walk(client, device, oidIfOperStatus)
+	}
+	if err == nil {
+		speed, err = walk(client, device, oidIfHighSpeed)
+	}
+	if err == nil {
+		duplex, err = walk(client, device, oidDot3Duplex)
+	}
+	if err == nil {
+		for idx, v := range ifNames {
+			name := str(v)
+			if name == "" {
+				continue
+			}
+			up := 0.0
+			if s, ok := oper[idx]; ok && num(s) == 1 {
+				up = 1
+			}
+			m.InterfaceUp.WithLabelValues(device.Hostname, name, device.Vendor).Set(up)
+			if s, ok := speed[idx]; ok {
+				m.InterfaceSpeedMbps.WithLabelValues(device.Hostname, name, device.Vendor).Set(num(s))
+			}
+			mode := "unknown"
+			if d, ok := duplex[idx]; ok {
+				switch num(d) {
+				case 2:
+					mode = "half"
+				case 3:
+					mode = "full"
+				}
+			}
+			m.InterfaceDuplex.WithLabelValues(device.Hostname, name, device.Vendor, mode).Set(1)
+		}
+	}
+	m.ObserveSection(device.Hostname, "interfaces", start, err)
+
+	// 3) Per-interface counters, each an in/out pair of ifIndex columns
+	walkPair := func(inOID, outOID string) (in, out map[string]gosnmp.SnmpPDU, err error) {
+		if ifErr != nil {
+			return nil, nil, ifErr
+		}
+		if in, err = walk(client, device, inOID); err != nil {
+			return nil, nil, err
+		}
+		out, err = walk(client, device, outOID)
+		return in, out, err
+	}
+
+	start = time.Now()
+	inErr, outErr, err := walkPair(oidIfInErrors, oidIfOutErrors)
+	if err == nil {
+		for idx, v := range ifNames {
+			name := str(v)
+			if name == "" {
+				continue
+			}
+			if e, ok := inErr[idx]; ok {
+				m.InterfaceInputErrors.WithLabelValues(device.Hostname, name).Set(num(e))
+			}
+			if e, ok := outErr[idx]; ok {
+				m.InterfaceOutputErrors.WithLabelValues(device.Hostname, name).Set(num(e))
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interface_errors", start, err)
+
+	start = time.Now()
+	inOctets, outOctets, err := walkPair(oidIfHCInOctets, oidIfHCOutOctets)
+	if err == nil {
+		for idx, v := range ifNames {
+			name := str(v)
+			if name == "" {
+				continue
+			}
+			if c, ok := inOctets[idx]; ok {
+				m.InterfaceInOctets.WithLabelValues(device.Hostname, name).Set(num(c))
+			}
+			if c, ok := outOctets[idx]; ok {
+				m.InterfaceOutOctets.WithLabelValues(device.Hostname, name).Set(num(c))
+			}
+		}
+	}
+	m.ObserveSection(device.Hostname, "interface_counters", start, err)
+
+	// 4) Routing and discovery tables; an empty table means the protocol
+	// is not running
+	for _, t := range []struct {
+		section string
+		oid     string
+		gauge   *metrics.GaugeVec
+	}{
+		{"bgp", oidBGPPeerState, m.BGPPeers},
+		{"ospf", oidOSPFNbrState, m.OSPFNeighbors},
+		{"lldp", oidLLDPRemChassis, m.LLDPNeighbors},
+	} {
+		start = time.Now()
+		rows, err := walk(client, device, t.oid)
+		if err == nil {
+			t.gauge.WithLabelValues(device.Hostname, device.Vendor).Set(float64(len(rows)))
+		}
+		m.ObserveSection(device.Hostname, t.section, start, err)
+	}
+
+	// 5) CPU: average load over all processors
+	start = time.Now()
+	load, err := walk(client, device, oidHrProcessorLoad)
+	if err == nil && len(load) > 0 {
+		total := 0.0
+		for _, v := range load {
+			total += num(v)
+		}
+		m.CPUUsage.WithLabelValues(device.Hostname, device.Vendor).Set(total / float64(len(load)))
+	}
+	m.ObserveSection(device.Hostname, "cpu", start, err)
+
+	// 6) Memory: the hrStorageRam entries of hrStorageTable
+	start = time.Now()
+	err = collectMemory(client, device, m)
+	m.ObserveSection(device.Hostname, "memory", start, err)
+
+	return ctx.Err()
+}
+
+func collectMemory(client *gosnmp.GoSNMP, device inventory.Device, m *metrics.Set) error {
+	types, err := walk(client, device, oidHrStorageType)
+	if err != nil {
+		return err
+	}
+	units, err := walk(client, device, oidHrStorageUnits)
+	if err != nil {
+		return err
+	}
+	sizes, err := walk(client, device, oidHrStorageSize)
+	if err != nil {
+		return err
+	}
+	used, err := walk(client, device, oidHrStorageUsed)
+	if err != nil {
+		return err
+	}
+
+	var totalBytes, usedBytes float64
+	for idx, t := range types {
+		if strings.TrimPrefix(str(t), ".") != oidHrStorageRAM {
+			continue
+		}
+		unit := num(units[idx])
+		totalBytes += num(sizes[idx]) * unit
+		usedBytes += num(used[idx]) * unit
+	}
+	if totalBytes == 0 {
+		return nil
+	}
+	m.SetMemory(device.Hostname, device.Vendor, totalBytes, usedBytes)
+	return nil
+}
+
+// connect builds an SNMP client from the device's netmetrics_snmp_* options.
+func connect(ctx context.Context, device inventory.Device) (*gosnmp.GoSNMP, error) {
+	opts := device.Options
+	port := DefaultPort
+	if v := opts["snmp_port"]; v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snmp_port %q", v)
+		}
+		port = p
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:  device.IP,
+		Port:    uint16(port),
+		Context: ctx,
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+
+	switch v := opts["snmp_version"]; v {
+	case "", "2", "2c", "v2c":
+		client.Version = gosnmp.Version2c
+		client.Community = opts["snmp_community"]
+		if client.Community == "" {
+			client.Community = "public"
+		}
+	case "3", "v3":
+		usm, flags, err := usmParameters(opts)
+		if err != nil {
+			return nil, err
+		}
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = flags
+		client.SecurityParameters = usm
+		client.ContextName = opts["snmp_context"]
+	default:
+		return nil, fmt.Errorf("unsupported snmp_version %q", v)
+	}
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("SNMP connect failed: %w", err)
+	}
+	return client, nil
+}
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"md5":    gosnmp.MD5,
+	"sha":    gosnmp.SHA,
+	"sha224": gosnmp.SHA224,
+	"sha256": gosnmp.SHA256,
+	"sha384": gosnmp.SHA384,
+	"sha512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"des":     gosnmp.DES,
+	"aes":     gosnmp.AES,
+	"aes192":  gosnmp.AES192,
+	"aes256":  gosnmp.AES256,
+	"aes192c": gosnmp.AES192C,
+	"aes256c": gosnmp.AES256C,
+}
+
+func usmParameters(opts map[string]string) (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:                 opts["snmp_username"],
+		AuthenticationPassphrase: opts["snmp_auth_password"],
+		PrivacyPassphrase:        opts["snmp_priv_password"],
+	}
+	if usm.UserName == "" {
+		return nil, 0, fmt.Errorf("snmp_username is required for SNMPv3")
+	}
+
+	level := opts["snmp_security_level"]
+	if level == "" {
+		switch {
+		case usm.PrivacyPassphrase != "":
+			level = "authPriv"
+		case usm.AuthenticationPassphrase != "":
+			level = "authNoPriv"
+		default:
+			level = "noAuthNoPriv"
+		}
+	}
+
+	var flags gosnmp.SnmpV3MsgFlags
+	switch strings.ToLower(level) {
+	case "noauthnopriv":
+		return usm, gosnmp.NoAuthNoPriv, nil
+	case "authnopriv":
+		flags = gosnmp.AuthNoPriv
+	case "authpriv":
+		flags = gosnmp.AuthPriv
+	default:
+		return nil, 0, fmt.Errorf("unknown snmp_security_level %q", level)
+	}
+
+	auth := strings.ToLower(opts["snmp_auth_protocol"])
+	if auth == "" {
+		auth = "sha"
+	}
+	var ok bool
+	if usm.AuthenticationProtocol, ok = authProtocols[auth]; !ok {
+		return nil, 0, fmt.Errorf("unknown snmp_auth_protocol %q", auth)
+	}
+	if flags == gosnmp.AuthPriv {
+		priv := strings.ToLower(opts["snmp_priv_protocol"])
+		if priv == "" {
+			priv = "aes"
+		}
+		if usm.PrivacyProtocol, ok = privProtocols[priv]; !ok {
+			return nil, 0, fmt.Errorf("unknown snmp_priv_protocol %q", priv)
+		}
+	}
+	return usm, flags, nil
+}
+
+// walk returns the rows of a table column keyed by their index, the part of
+// the OID after the column. A missing table is not an error.
+func walk(client *gosnmp.GoSNMP, device inventory.Device, oid string) (map[string]gosnmp.SnmpPDU, error) {
+	pdus, err := client.BulkWalkAll(oid)
+	if err != nil {
+		return nil, fmt.Errorf("SNMP walk %s: %w", oid, err)
+	}
+	prefix := "." + oid + "."
+	rows := make(map[string]gosnmp.SnmpPDU, len(pdus))
+	for _, pdu := range pdus {
+		if idx := strings.TrimPrefix(pdu.Name, prefix); idx != pdu.Name {
+			rows[idx] = pdu
+		}
+	}
+	if os.Getenv("NETMETRICS_DEBUG") == "1" {
+		fmt.Printf("DEBUG SNMP RESULT from %s (%s): %d rows\n", device.Hostname, oid, len(rows))
+	}
+	return rows, nil
+}
+
+func str(pdu gosnmp.SnmpPDU) string {
+	switch v := pdu.Value.(type) {
+	case []byte:
+		return strings.TrimRight(string(v), "\x00")
+	case string:
+		return v
+	}
+	return ""
+}
+
+func num(pdu gosnmp.SnmpPDU) float64 {
+	switch pdu.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(gosnmp.ToBigInt(pdu.Value)).Float64()
+	return f
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
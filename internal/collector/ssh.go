@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+
+	"netmetrics_exporter/internal/inventory"
+)
+
+// PasswordAuth answers both password and keyboard-interactive prompts with
+// the device password, since network OSes use either.
+func PasswordAuth(device inventory.Device) []ssh.AuthMethod {
+	return []ssh.AuthMethod{
+		ssh.Password(device.Password),
+		ssh.KeyboardInteractive(
+			func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range questions {
+					answers[i] = device.Password
+				}
+				return answers, nil
+			},
+		),
+	}
+}
+
+// DialSSH is ssh.Dial with a context: the TCP connect and the SSH handshake
+// both stop at the context deadline.
+func DialSSH(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	d := net.Dialer{Timeout: config.Timeout}
+	tcpConn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		tcpConn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(tcpConn, addr, config)
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
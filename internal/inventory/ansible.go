@@ -0,0 +1,235 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ansibleInventory is the format-independent form of an Ansible inventory:
+// a tree of groups, each with its own hosts, vars and child groups. Both the
+// INI and the YAML loaders fill one and let it resolve the devices.
+type ansibleInventory struct {
+	groups map[string]*ansibleGroup
+
+	// Vars read from the group_vars/ and host_vars/ directories.
+	groupFileVars map[string]map[string]interface{}
+	hostFileVars  map[string]map[string]interface{}
+
+	vaultPassword []byte
+}
+
+type ansibleGroup struct {
+	name     string
+	hosts    map[string]map[string]interface{}
+	vars     map[string]interface{}
+	children []string
+	parents  []string
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	inv := &ansibleInventory{
+		groups:        make(map[string]*ansibleGroup),
+		groupFileVars: make(map[string]map[string]interface{}),
+		hostFileVars:  make(map[string]map[string]interface{}),
+	}
+	inv.group("all")
+	return inv
+}
+
+// group returns the named group, creating it on first use.
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{
+			name:  name,
+			hosts: make(map[string]map[string]interface{}),
+			vars:  make(map[string]interface{}),
+		}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+// addHost declares hostname as a member of group, with the vars written next
+// to it.
+func (inv *ansibleInventory) addHost(group, hostname string, vars map[string]interface{}) {
+	g := inv.group(group)
+	if g.hosts[hostname] == nil {
+		g.hosts[hostname] = make(map[string]interface{})
+	}
+	for k, v := range vars {
+		g.hosts[hostname][k] = v
+	}
+}
+
+// addChild makes child a sub-group of parent.
+func (inv *ansibleInventory) addChild(parent, child string) {
+	p, c := inv.group(parent), inv.group(child)
+	p.children = append(p.children, child)
+	c.parents = append(c.parents, parent)
+}
+
+// devices resolves every host once, no matter how many groups it appears
+// in, following Ansible's variable precedence from lowest to highest:
+//
+//  1. group vars from the inventory file, "all" first
+//  2. group_vars/all, then the other group_vars/ files
+//  3. host vars from the inventory file
+//  4. host_vars/ files
+//
+// Within steps 1 and 2 groups are ordered by depth (parents before
+// children), then by ansible_group_priority, then by name, so the most
+// specific group wins.
+func (inv *ansibleInventory) devices() ([]Device, error) {
+	depth, err := inv.depths()
+	if err != nil {
+		return nil, err
+	}
+
+	memberOf := make(map[string][]string)
+	for name, g := range inv.groups {
+		for host := range g.hosts {
+			memberOf[host] = append(memberOf[host], name)
+		}
+	}
+
+	hostnames := make([]string, 0, len(memberOf))
+	for host := range memberOf {
+		hostnames = append(hostnames, host)
+	}
+	sort.Strings(hostnames)
+
+	var devices []Device
+	for _, host := range hostnames {
+		groups := inv.ancestors(memberOf[host])
+		sort.Slice(groups, func(i, j int) bool {
+			gi, gj := groups[i], groups[j]
+			if depth[gi] != depth[gj] {
+				return depth[gi] < depth[gj]
+			}
+			if pi, pj := inv.priority(gi), inv.priority(gj); pi != pj {
+				return pi < pj
+			}
+			return gi < gj
+		})
+
+		vars := make(map[string]interface{})
+		for _, name := range groups {
+			vars = mergeVars(vars, inv.groups[name].vars)
+		}
+		for _, name := range groups {
+			vars = mergeVars(vars, inv.groupFileVars[name])
+		}
+		direct := memberOf[host]
+		sort.Strings(direct)
+		for _, name := range direct {
+			vars = mergeVars(vars, inv.groups[name].hosts[host])
+		}
+		vars = mergeVars(vars, inv.hostFileVars[host])
+
+		devices = append(devices, buildDevice(host, vars, nil))
+	}
+	return devices, nil
+}
+
+// decryptVars decrypts every inline !vault value in the inventory.
+func (inv *ansibleInventory) decryptVars() error {
+	for name, g := range inv.groups {
+		if err := decryptValues(g.vars, inv.vaultPassword); err != nil {
+			return fmt.Errorf("group %s: %w", name, err)
+		}
+		for host, vars := range g.hosts {
+			if err := decryptValues(vars, inv.vaultPassword); err != nil {
+				return fmt.Errorf("host %s: %w", host, err)
+			}
+		}
+	}
+	for name, vars := range inv.groupFileVars {
+		if err := decryptValues(vars, inv.vaultPassword); err != nil {
+			return fmt.Errorf("group_vars/%s: %w", name, err)
+		}
+	}
+	for host, vars := range inv.hostFileVars {
+		if err := decryptValues(vars, inv.vaultPassword); err != nil {
+			return fmt.Errorf("host_vars/%s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// priority returns the ansible_group_priority of a group (default 1). A
+// higher priority is applied later and therefore wins among siblings.
+func (inv *ansibleInventory) priority(group string) int {
+	p := inv.groups[group].vars["ansible_group_priority"]
+	if fv, ok := inv.groupFileVars[group]["ansible_group_priority"]; ok {
+		p = fv
+	}
+	switch v := p.(type) {
+	case int:
+		return v
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return 1
+}
+
+// ancestors returns the given groups, every group above them and "all".
+func (inv *ansibleInventory) ancestors(groups []string) []string {
+	seen := map[string]bool{"all": true}
+	out := []string{"all"}
+	queue := append([]string(nil), groups...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+		queue = append(queue, inv.groups[name].parents...)
+	}
+	return out
+}
+
+// depths returns the distance of every group from "all", using the longest
+// path so a group is always deeper than each of its parents. A group that is
+// nobody's child hangs directly below "all".
+func (inv *ansibleInventory) depths() (map[string]int, error) {
+	depth := map[string]int{"all": 0}
+	visiting := make(map[string]bool)
+
+	var visit func(name string) (int, error)
+	visit = func(name string) (int, error) {
+		if d, ok := depth[name]; ok {
+			return d, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("group %q is its own ancestor", name)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		d := 1
+		for _, parent := range inv.groups[name].parents {
+			pd, err := visit(parent)
+			if err != nil {
+				return 0, err
+			}
+			if pd+1 > d {
+				d = pd + 1
+			}
+		}
+		depth[name] = d
+		return d, nil
+	}
+
+	for name := range inv.groups {
+		if _, err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return depth, nil
+}
@@ -0,0 +1,200 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LoadAnsibleINI reads an Ansible inventory in INI format:
+//
+//	[spines]
+//	spine[1:2] ansible_host=10.0.0.1 ansible_network_os=eos
+//
+//	[leafs:vars]
+//	ansible_user=admin
+//
+//	[dc1:children]
+//	spines
+//	leafs
+func LoadAnsibleINI(path string) ([]Device, error) {
+	return LoadFile(path, LoadOptions{Format: FormatAnsibleINI})
+}
+
+func readAnsibleINI(data []byte) (*ansibleInventory, error) {
+	inv := newAnsibleInventory()
+
+	// Hosts before the first section belong to "ungrouped".
+	section, kind := "ungrouped", "hosts"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section header %q", lineNo, line)
+			}
+			section, kind = line[1:len(line)-1], "hosts"
+			if i := strings.LastIndex(section, ":"); i >= 0 {
+				section, kind = section[:i], section[i+1:]
+			}
+			if kind != "hosts" && kind != "vars" && kind != "children" {
+				return nil, fmt.Errorf("line %d: unknown section type %q", lineNo, kind)
+			}
+			inv.group(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value in [%s:vars]", lineNo, section)
+			}
+			inv.group(section).vars[strings.TrimSpace(key)] = unquoteINI(strings.TrimSpace(value))
+
+		case "children":
+			inv.addChild(section, line)
+
+		case "hosts":
+			fields, err := splitINIFields(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			vars := make(map[string]interface{})
+			for _, f := range fields[1:] {
+				key, value, ok := strings.Cut(f, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected key=value after host, got %q", lineNo, f)
+				}
+				vars[key] = value
+			}
+			hosts, err := expandHostPattern(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			for _, host := range hosts {
+				inv.addHost(section, host, vars)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// splitINIFields splits a host line on whitespace, honouring single and
+// double quotes and dropping a trailing # comment.
+func splitINIFields(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	var quote rune
+	inField := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inField = r, true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		case r == '#' && !inField:
+			return fields, nil
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+func unquoteINI(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// expandHostPattern expands Ansible host ranges such as leaf[01:04] or
+// rack-[a:c]-tor, keeping the zero padding of numeric ranges.
+func expandHostPattern(pattern string) ([]string, error) {
+	open := strings.Index(pattern, "[")
+	if open < 0 {
+		return []string{pattern}, nil
+	}
+	end := strings.Index(pattern[open:], "]")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated range in host pattern %q", pattern)
+	}
+	end += open
+	prefix, spec, suffix := pattern[:open], pattern[open+1:end], pattern[end+1:]
+
+	bounds := strings.Split(spec, ":")
+	if len(bounds) < 2 || len(bounds) > 3 {
+		return nil, fmt.Errorf("invalid range [%s] in host pattern %q", spec, pattern)
+	}
+	step := 1
+	if len(bounds) == 3 {
+		s, err := strconv.Atoi(bounds[2])
+		if err != nil || s <= 0 {
+			return nil, fmt.Errorf("invalid step in host pattern %q", pattern)
+		}
+		step = s
+	}
+
+	var items []string
+	from, errFrom := strconv.Atoi(bounds[0])
+	to, errTo := strconv.Atoi(bounds[1])
+	switch {
+	case errFrom == nil && errTo == nil:
+		width := 0
+		if len(bounds[0]) > 1 && bounds[0][0] == '0' {
+			width = len(bounds[0])
+		}
+		for i := from; i <= to; i += step {
+			items = append(items, fmt.Sprintf("%0*d", width, i))
+		}
+	case len(bounds[0]) == 1 && len(bounds[1]) == 1:
+		for c := bounds[0][0]; c <= bounds[1][0]; c += byte(step) {
+			items = append(items, string(c))
+		}
+	default:
+		return nil, fmt.Errorf("invalid range [%s] in host pattern %q", spec, pattern)
+	}
+
+	rest, err := expandHostPattern(suffix)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, item := range items {
+		for _, r := range rest {
+			hosts = append(hosts, prefix+item+r)
+		}
+	}
+	return hosts, nil
+}
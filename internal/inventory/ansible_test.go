@@ -0,0 +1,381 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{pattern: "spine1", want: []string{"spine1"}},
+		{pattern: "spine[1:3]", want: []string{"spine1", "spine2", "spine3"}},
+		{pattern: "leaf[01:04].dc1.example.net", want: []string{
+			"leaf01.dc1.example.net", "leaf02.dc1.example.net",
+			"leaf03.dc1.example.net", "leaf04.dc1.example.net",
+		}},
+		{pattern: "leaf[08:10]", want: []string{"leaf08", "leaf09", "leaf10"}},
+		{pattern: "sw[0:6:2]", want: []string{"sw0", "sw2", "sw4", "sw6"}},
+		{pattern: "rack-[a:c]-tor", want: []string{"rack-a-tor", "rack-b-tor", "rack-c-tor"}},
+		{pattern: "r[1:2]-s[a:b]", want: []string{"r1-sa", "r1-sb", "r2-sa", "r2-sb"}},
+		{pattern: "spine[1:2", wantErr: true},
+		{pattern: "spine[1]", wantErr: true},
+		{pattern: "spine[1:4:0]", wantErr: true},
+		{pattern: "spine[aa:bb]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := expandHostPattern(tt.pattern)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expandHostPattern(%q) = %v, want error", tt.pattern, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandHostPattern(%q) error = %v", tt.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandHostPattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSplitINIFields(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "spine1 ansible_host=10.0.0.1", want: []string{"spine1", "ansible_host=10.0.0.1"}},
+		{line: "spine1\tansible_host=10.0.0.1  # core", want: []string{"spine1", "ansible_host=10.0.0.1"}},
+		{line: `leaf1 ansible_password="p@ss word" site='dc 1'`, want: []string{"leaf1", "ansible_password=p@ss word", "site=dc 1"}},
+		{line: "leaf1 ansible_password=a#b", want: []string{"leaf1", "ansible_password=a#b"}},
+		{line: `leaf1 ansible_password="open`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitINIFields(tt.line)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitINIFields(%q) = %v, want error", tt.line, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitINIFields(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitINIFields(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestReadAnsibleINIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "unclosed section", data: "[spines\nspine1", wantErr: "line 1: malformed section header"},
+		{name: "unknown section type", data: "[spines:hosts2]", wantErr: "line 1: unknown section type"},
+		{name: "vars without value", data: "[spines:vars]\nansible_user", wantErr: "line 2: expected key=value"},
+		{name: "host var without value", data: "[spines]\nspine1 ansible_host", wantErr: "line 2: expected key=value after host"},
+		{name: "bad range", data: "[spines]\nspine[1:xx]", wantErr: "line 2: invalid range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAnsibleINI([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("readAnsibleINI() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGroupPrecedence checks the order vars are applied in: deeper groups
+// over their parents, ansible_group_priority and then the name among
+// siblings, group_vars/ files over inventory group vars, and host vars over
+// everything. netmetrics_* vars are used so they can be read back from
+// Device.Options.
+func TestGroupPrecedence(t *testing.T) {
+	const ini = `
+[all:vars]
+netmetrics_site=all
+netmetrics_role=all
+netmetrics_owner=all
+
+[dc1:children]
+spines
+
+[dc1:vars]
+netmetrics_site=dc1
+netmetrics_role=dc1
+
+[spines]
+spine1
+spine2 netmetrics_role=host
+
+[spines:vars]
+netmetrics_role=spines
+netmetrics_site=spines
+
+[aaa]
+spine1
+
+[aaa:vars]
+netmetrics_owner=aaa
+
+[zzz]
+spine1
+
+[zzz:vars]
+netmetrics_owner=zzz
+
+[prio]
+spine2
+
+[prio:vars]
+ansible_group_priority=10
+netmetrics_owner=prio
+
+[other]
+spine2
+
+[other:vars]
+netmetrics_owner=other
+`
+	inv, err := readAnsibleINI([]byte(ini))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.groupFileVars["all"] = map[string]interface{}{"netmetrics_from_file": "all"}
+	inv.groupFileVars["dc1"] = map[string]interface{}{"netmetrics_site": "dc1-file", "netmetrics_from_file": "dc1"}
+
+	devices, err := inv.devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Device)
+	for _, d := range devices {
+		got[d.Hostname] = d
+	}
+	if len(got) != 2 {
+		t.Fatalf("got devices %v, want spine1 and spine2", devices)
+	}
+
+	tests := []struct {
+		host, option, want string
+	}{
+		// spines is below dc1, dc1 below all.
+		{"spine1", "role", "spines"},
+		// A group_vars/ file beats every inventory group var, even one set
+		// on a deeper group.
+		{"spine1", "site", "dc1-file"},
+		{"spine1", "from_file", "dc1"},
+		// Siblings at the same depth and priority apply by name.
+		{"spine1", "owner", "zzz"},
+		// A higher ansible_group_priority wins over the name.
+		{"spine2", "owner", "prio"},
+		// Inventory host vars beat every group var.
+		{"spine2", "role", "host"},
+	}
+	for _, tt := range tests {
+		if v := got[tt.host].Options[tt.option]; v != tt.want {
+			t.Errorf("%s: %s = %q, want %q", tt.host, tt.option, v, tt.want)
+		}
+	}
+}
+
+func TestGroupCycle(t *testing.T) {
+	inv, err := readAnsibleINI([]byte("[a:children]\nb\n[b:children]\na\n[a]\nhost1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inv.devices(); err == nil || !strings.Contains(err.Error(), "its own ancestor") {
+		t.Errorf("devices() error = %v, want a cycle error", err)
+	}
+}
+
+func TestLoadAnsibleINI(t *testing.T) {
+	dir := writeInventory(t, map[string]string{
+		// The INI example from the README.
+		"hosts": `
+[spines]
+spine1 ansible_host=192.168.100.11
+spine2 ansible_host=192.168.100.12
+
+[spines:vars]
+ansible_network_os=eos
+
+# Ranges expand the host name only; without ansible_host each leaf is
+# reached by its name, e.g. leaf01.dc1.example.net
+[leafs]
+leaf[01:04].dc1.example.net
+
+[leafs:vars]
+ansible_network_os=nokia.srlinux.srlinux
+
+[dc1:children]
+spines
+leafs
+
+[dc1:vars]
+ansible_user=admin
+ansible_password=admin
+`,
+		"group_vars/all.yml":                    "ansible_user: readonly\nnetmetrics_poll_interval: 30\n",
+		"group_vars/leafs.yml":                  "ansible_httpapi_validate_certs: false\n",
+		"group_vars/spines/connection.yml":      "ansible_connection: httpapi\nansible_port: 8443\n",
+		"group_vars/spines/tls.yaml":            "ansible_httpapi_use_ssl: true\n",
+		"host_vars/spine2":                      "ansible_password: spine2-secret\n",
+		"host_vars/leaf03.dc1.example.net.json": `{"ansible_host": "10.0.0.3"}`,
+		// Not a group or host in the inventory.
+		"host_vars/unknown.yml": "ansible_host: 10.9.9.9\n",
+	})
+
+	devices, err := LoadFile(filepath.Join(dir, "hosts"), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Device{
+		{Hostname: "leaf01.dc1.example.net", IP: "leaf01.dc1.example.net", Vendor: "srlinux", Password: "admin"},
+		{Hostname: "leaf02.dc1.example.net", IP: "leaf02.dc1.example.net", Vendor: "srlinux", Password: "admin"},
+		{Hostname: "leaf03.dc1.example.net", IP: "10.0.0.3", Vendor: "srlinux", Password: "admin"},
+		{Hostname: "leaf04.dc1.example.net", IP: "leaf04.dc1.example.net", Vendor: "srlinux", Password: "admin"},
+		{Hostname: "spine1", IP: "192.168.100.11", Vendor: "arista", Password: "admin",
+			Connection: "httpapi", Port: 8443, Scheme: "https", ValidateCerts: true},
+		{Hostname: "spine2", IP: "192.168.100.12", Vendor: "arista", Password: "spine2-secret",
+			Connection: "httpapi", Port: 8443, Scheme: "https", ValidateCerts: true},
+	}
+	for i := range want {
+		// group_vars/all beats the inventory's [dc1:vars].
+		want[i].Username = "readonly"
+		want[i].Interval = 30 * time.Second
+		want[i].Options = map[string]string{"poll_interval": "30"}
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("LoadFile() =\n%+v\nwant\n%+v", devices, want)
+	}
+}
+
+func TestLoadAnsibleYAML(t *testing.T) {
+	dir := writeInventory(t, map[string]string{
+		"inventory.yml": `
+all:
+  vars:
+    ansible_user: admin
+  children:
+    dc1:
+      children:
+        spines:
+          vars:
+            ansible_network_os: eos
+          hosts:
+            spine1:
+              ansible_host: 192.168.100.11
+              ansible_password: !vault |
+` + indent(vault11, "                ") + `
+            spine2:
+              ansible_host: 192.168.100.12
+    junos:
+      vars:
+        ansible_network_os: junipernetworks.junos.junos
+        ansible_connection: netconf
+        ansible_port: 830
+      hosts:
+        spine2:
+        mx1:
+`,
+		"group_vars/dc1.yml": "ansible_password: !vault |\n" + indent(vault12, "  ") + "\n",
+		// Encrypted as a whole with "ansible-vault encrypt".
+		"host_vars/mx1.yml": vaultFile + "\n",
+	})
+
+	devices, err := LoadFile(filepath.Join(dir, "inventory.yml"), LoadOptions{VaultPassword: []byte(vaultPassword)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Device{
+		{Hostname: "mx1", IP: "mx1", Vendor: "juniper", Username: "admin", Password: "from-vault-file",
+			Connection: "netconf", Port: 830, ValidateCerts: true},
+		{Hostname: "spine1", IP: "192.168.100.11", Vendor: "arista", Username: "admin", Password: "n3tw0rk-1.1", ValidateCerts: true},
+		// spines sits deeper than junos, so its ansible_network_os wins;
+		// junos still adds the vars spines does not set.
+		{Hostname: "spine2", IP: "192.168.100.12", Vendor: "arista", Username: "admin", Password: "n3tw0rk-1.2",
+			Connection: "netconf", Port: 830, ValidateCerts: true},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("LoadFile() =\n%+v\nwant\n%+v", devices, want)
+	}
+}
+
+func TestLoadFileVaultErrors(t *testing.T) {
+	inline := "all:\n  hosts:\n    r1:\n      ansible_password: !vault |\n" + indent(vault11, "        ") + "\n"
+	tests := []struct {
+		name     string
+		files    map[string]string
+		password string
+		wantErr  string
+	}{
+		{
+			name:     "inline value, wrong password",
+			files:    map[string]string{"inventory": inline},
+			password: "not-it",
+			wantErr:  "host r1: ansible_password: vault: decryption failed (wrong password?)",
+		},
+		{
+			name:    "inline value, no password",
+			files:   map[string]string{"inventory": inline},
+			wantErr: "no vault password is configured",
+		},
+		{
+			name:     "encrypted vars file, wrong password",
+			files:    map[string]string{"inventory": "[all]\nr1\n", "host_vars/r1": vaultFile},
+			password: "not-it",
+			wantErr:  "wrong password",
+		},
+		{
+			name:    "encrypted inventory, no password",
+			files:   map[string]string{"inventory": vaultFile},
+			wantErr: "vault-encrypted but no vault password is configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeInventory(t, tt.files)
+			var password []byte
+			if tt.password != "" {
+				password = []byte(tt.password)
+			}
+			_, err := LoadFile(filepath.Join(dir, "inventory"), LoadOptions{VaultPassword: password})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeInventory writes files, keyed by slash-separated path, into a fresh
+// directory and returns it.
+func writeInventory(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,112 @@
+package inventory
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// varsDirs are the directories Ansible reads next to an inventory file.
+var varsDirs = []string{"group_vars", "host_vars"}
+
+// readVarsDirs loads the group_vars/ and host_vars/ directories found in dir.
+// Each entry is named after a group or host and is either a vars file (no
+// extension, .yml, .yaml or .json) or a directory whose files are merged in
+// lexical order. Entries for unknown groups or hosts are ignored.
+func (inv *ansibleInventory) readVarsDirs(dir string) error {
+	groups := make(map[string]bool)
+	hosts := make(map[string]bool)
+	for name, g := range inv.groups {
+		groups[name] = true
+		for host := range g.hosts {
+			hosts[host] = true
+		}
+	}
+
+	if err := readVarsDir(filepath.Join(dir, "group_vars"), groups, inv.groupFileVars, inv.vaultPassword); err != nil {
+		return err
+	}
+	return readVarsDir(filepath.Join(dir, "host_vars"), hosts, inv.hostFileVars, inv.vaultPassword)
+}
+
+func readVarsDir(dir string, known map[string]bool, into map[string]map[string]interface{}, vaultPassword []byte) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		base, ok := varsFileBase(name)
+		if e.IsDir() {
+			base, ok = name, true
+		}
+		if !ok || !known[base] {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != path && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := varsFileBase(d.Name()); !ok || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			vars, err := readVarsFile(p, vaultPassword)
+			if err != nil {
+				return err
+			}
+			into[base] = mergeVars(into[base], vars)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varsFileBase strips the extensions Ansible accepts for vars files and
+// reports whether name is one.
+func varsFileBase(name string) (string, bool) {
+	switch ext := filepath.Ext(name); ext {
+	case ".yml", ".yaml", ".json":
+		return strings.TrimSuffix(name, ext), true
+	case "":
+		return name, true
+	}
+	return "", false
+}
+
+func readVarsFile(path string, vaultPassword []byte) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decryptFile(path, data, vaultPassword); err != nil {
+		return nil, err
+	}
+	var vars map[string]interface{}
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse vars file %s: %w", path, err)
+	}
+	return vars, nil
+}
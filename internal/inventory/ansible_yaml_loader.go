@@ -1,50 +1,62 @@
 package inventory
 
 import (
-	"io/ioutil"
-	"log"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
-type AnsibleYAML struct {
-	All struct {
-		Hosts    map[string]map[string]interface{} `yaml:"hosts"`
-		Vars     map[string]interface{}            `yaml:"vars"`
-		Children map[string]struct {
-			Vars  map[string]interface{}            `yaml:"vars"`
-			Hosts map[string]map[string]interface{} `yaml:"hosts"`
-		} `yaml:"children"`
-	} `yaml:"all"`
+// AnsibleYAML is an Ansible YAML inventory: top-level groups (usually just
+// "all"), each of which can nest further groups under children to any depth.
+type AnsibleYAML map[string]*AnsibleYAMLGroup
+
+type AnsibleYAMLGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Children map[string]*AnsibleYAMLGroup      `yaml:"children"`
 }
 
-func LoadAnsibleYAML(path string) []Device {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		log.Fatalf("Failed to read Ansible inventory file: %v", err)
-	}
+func LoadAnsibleYAML(path string) ([]Device, error) {
+	return LoadFile(path, LoadOptions{Format: FormatAnsibleYAML})
+}
 
+func readAnsibleYAML(data []byte) (*ansibleInventory, error) {
 	var ansibleInv AnsibleYAML
 	if err := yaml.Unmarshal(data, &ansibleInv); err != nil {
-		log.Fatalf("Failed to parse Ansible YAML: %v", err)
-	}
-
-	var devices []Device
-
-	// Parse all.hosts
-	for hostname, vars := range ansibleInv.All.Hosts {
-		devices = append(devices, buildDevice(hostname, vars, ansibleInv.All.Vars))
+		return nil, fmt.Errorf("failed to parse Ansible YAML: %w", err)
 	}
 
-	// Parse all.children
-	for _, group := range ansibleInv.All.Children {
-		for hostname, hostVars := range group.Hosts {
-			merged := mergeVars(group.Vars, hostVars)
-			devices = append(devices, buildDevice(hostname, merged, ansibleInv.All.Vars))
+	inv := newAnsibleInventory()
+	for name, group := range ansibleInv {
+		if name != "all" {
+			inv.addChild("all", name)
 		}
+		addYAMLGroup(inv, name, group)
 	}
+	return inv, nil
+}
 
-	return devices
+// addYAMLGroup adds group and, recursively, all of its children. A group may
+// appear under several parents; its hosts and vars are merged.
+func addYAMLGroup(inv *ansibleInventory, name string, group *AnsibleYAMLGroup) {
+	g := inv.group(name)
+	if group == nil {
+		return
+	}
+	for k, v := range group.Vars {
+		g.vars[k] = v
+	}
+	for hostname, vars := range group.Hosts {
+		inv.addHost(name, hostname, vars)
+	}
+	for child, sub := range group.Children {
+		inv.addChild(name, child)
+		addYAMLGroup(inv, child, sub)
+	}
 }
 
 func getString(v interface{}) string {
@@ -53,6 +65,56 @@ func getString(v interface{}) string {
 	}
 	return ""
 }
+
+// getDuration accepts either a Go duration string ("45s") or a plain number
+// of seconds, which is how most inventories spell timeouts.
+func getDuration(v interface{}) time.Duration {
+	switch d := v.(type) {
+	case int:
+		return time.Duration(d) * time.Second
+	case float64:
+		return time.Duration(d * float64(time.Second))
+	case string:
+		if parsed, err := time.ParseDuration(d); err == nil {
+			return parsed
+		}
+		if secs, err := strconv.ParseFloat(d, 64); err == nil {
+			return time.Duration(secs * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// getInt accepts an integer or its string form, as written by INI inventories.
+func getInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case string:
+		if parsed, err := strconv.Atoi(n); err == nil {
+			return parsed
+		}
+	}
+	return 0
+}
+
+// getBool accepts a YAML boolean or one of the spellings Ansible treats as a
+// boolean in INI inventories. ok is false when v is unset or not a boolean.
+func getBool(v interface{}) (value, ok bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		switch strings.ToLower(b) {
+		case "yes", "true", "on", "1", "y", "t":
+			return true, true
+		case "no", "false", "off", "0", "n", "f":
+			return false, true
+		}
+	}
+	return false, false
+}
+
 func buildDevice(hostname string, vars map[string]interface{}, global map[string]interface{}) Device {
 	// Precedence: host > group > global
 	all := mergeVars(global, vars)
@@ -60,24 +122,58 @@ func buildDevice(hostname string, vars map[string]interface{}, global map[string
 	networkOS := getString(all["ansible_network_os"])
 	vendor := NormalizeVendor(networkOS)
 
-	protocol := "unknown"
-	switch vendor {
-	case "arista":
-		protocol = "eapi"
-	case "srlinux":
-		protocol = "jsonrpc"
-	case "cisco":
-		protocol = "restconf"
+	dev := Device{
+		Hostname:   hostname,
+		IP:         getString(all["ansible_host"]),
+		Username:   getString(all["ansible_user"]),
+		Password:   getString(all["ansible_password"]),
+		Vendor:     vendor,
+		Protocol:   getString(all["netmetrics_protocol"]),
+		Connection: getString(all["ansible_connection"]),
+		Port:       getInt(all["ansible_httpapi_port"]),
+		Interval:   getDuration(all["netmetrics_poll_interval"]),
+		Timeout:    getDuration(all["netmetrics_poll_timeout"]),
+	}
+
+	// Like Ansible, connect to the inventory hostname when ansible_host is
+	// not set.
+	if dev.IP == "" {
+		dev.IP = hostname
+	}
+
+	for k, v := range all {
+		if name := strings.TrimPrefix(k, "netmetrics_"); name != k && v != nil {
+			if dev.Options == nil {
+				dev.Options = make(map[string]string)
+			}
+			dev.Options[name] = fmt.Sprint(v)
+		}
+	}
+
+	// ansible_port is the port of whatever ansible_connection talks to: SSH
+	// for network_cli (and by default), the API for httpapi or netconf.
+	switch dev.Connection {
+	case "", "ssh", "paramiko", "network_cli", "ansible.netcommon.network_cli":
+		dev.SSHPort = getInt(all["ansible_port"])
+	default:
+		if dev.Port == 0 {
+			dev.Port = getInt(all["ansible_port"])
+		}
 	}
 
-	return Device{
-		Hostname: hostname,
-		IP:       getString(all["ansible_host"]),
-		Username: getString(all["ansible_user"]),
-		Password: getString(all["ansible_password"]),
-		Vendor:   vendor,
-		Protocol: protocol,
+	if useSSL, ok := getBool(all["ansible_httpapi_use_ssl"]); ok {
+		dev.Scheme = "http"
+		if useSSL {
+			dev.Scheme = "https"
+		}
 	}
+	// Like Ansible, verify certificates unless told otherwise.
+	dev.ValidateCerts = true
+	if validate, ok := getBool(all["ansible_httpapi_validate_certs"]); ok {
+		dev.ValidateCerts = validate
+	}
+
+	return dev
 }
 
 func mergeVars(a, b map[string]interface{}) map[string]interface{} {
@@ -92,10 +188,28 @@ func mergeVars(a, b map[string]interface{}) map[string]interface{} {
 }
 
 var vendorMap = map[string]string{
-	"eos":                   "arista",
-	"ios":                   "cisco",
-	"junos":                 "juniper",
-	"nokia.srlinux.srlinux": "srlinux",
+	"eos":                            "arista",
+	"ios":                            "cisco",
+	"junos":                          "juniper",
+	"junipernetworks.junos.junos":    "juniper",
+	"cisco.nxos.nxos":                "nxos",
+	"cisco.iosxr.iosxr":              "iosxr",
+	"nokia.srlinux.srlinux":          "srlinux",
+	"nokia.sros.sros":                "sros",
+	"nvidia.nvue.nvue":               "cumulus",
+	"dellemc.enterprise_sonic.sonic": "sonic",
+}
+
+// Aliases returns the ansible_network_os values that map to vendor.
+func Aliases(vendor string) []string {
+	var out []string
+	for os, v := range vendorMap {
+		if v == vendor {
+			out = append(out, os)
+		}
+	}
+	sort.Strings(out)
+	return out
 }
 
 func NormalizeVendor(os string) string {
@@ -0,0 +1,87 @@
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Inventory file formats accepted by LoadFile.
+const (
+	FormatAuto        = "auto"
+	FormatNative      = "native"
+	FormatAnsibleYAML = "ansible-yaml"
+	FormatAnsibleINI  = "ansible-ini"
+)
+
+// Formats lists the values accepted by LoadFile, for flag help.
+var Formats = []string{FormatAuto, FormatNative, FormatAnsibleYAML, FormatAnsibleINI}
+
+// LoadOptions controls how LoadFile reads an inventory.
+type LoadOptions struct {
+	// Format is one of Formats. Empty and FormatAuto pick the format from
+	// the file content, never from the file name.
+	Format string
+
+	// VaultPassword decrypts inline !vault values as well as inventory and
+	// vars files encrypted as a whole. Without it, encrypted data is an
+	// error.
+	VaultPassword []byte
+}
+
+// LoadFile reads the inventory at path.
+func LoadFile(path string, opts LoadOptions) ([]Device, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading inventory file: %w", err)
+	}
+	if data, err = decryptFile(path, data, opts.VaultPassword); err != nil {
+		return nil, err
+	}
+
+	format := opts.Format
+	if format == "" || format == FormatAuto {
+		format = DetectFormat(data)
+	}
+	var inv *ansibleInventory
+	switch format {
+	case FormatNative:
+		return parseNative(data, opts.VaultPassword)
+	case FormatAnsibleYAML:
+		inv, err = readAnsibleYAML(data)
+	case FormatAnsibleINI:
+		inv, err = readAnsibleINI(data)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Like Ansible, pick up group_vars/ and host_vars/ next to the file.
+	inv.vaultPassword = opts.VaultPassword
+	if err := inv.readVarsDirs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := inv.decryptVars(); err != nil {
+		return nil, err
+	}
+	return inv.devices()
+}
+
+// DetectFormat guesses the inventory format from its content. A YAML mapping
+// with a top-level "devices" list is the native format, any other mapping is
+// an Ansible YAML inventory, and everything else (section headers, bare
+// "host key=value" lines) is treated as Ansible INI.
+func DetectFormat(data []byte) string {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc == nil {
+		return FormatAnsibleINI
+	}
+	if _, ok := doc["devices"]; ok {
+		return FormatNative
+	}
+	return FormatAnsibleYAML
+}
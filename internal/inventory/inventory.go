@@ -1,5 +1,7 @@
 package inventory
 
+import "time"
+
 type Device struct {
 	Hostname string
 	IP       string
@@ -7,4 +9,27 @@ type Device struct {
 	Protocol string
 	Username string
 	Password string
+
+	// Connection is the Ansible connection plugin (httpapi, netconf,
+	// network_cli, ...), empty when the inventory does not say.
+	Connection string
+
+	// Port is the management API port and SSHPort the port used for SSH.
+	// Zero means the protocol default.
+	Port    int
+	SSHPort int
+
+	// Scheme is "http" or "https"; empty lets the collector pick its
+	// default. ValidateCerts turns on TLS certificate verification.
+	Scheme        string
+	ValidateCerts bool
+
+	// Options holds the netmetrics_* inventory variables, without the
+	// prefix, for collector-specific settings such as netmetrics_gnmi_mode.
+	Options map[string]string
+
+	// Interval and Timeout override the scheduler defaults for this device.
+	// Zero means "use the default".
+	Interval time.Duration
+	Timeout  time.Duration
 }
@@ -1,8 +1,8 @@
 package inventory
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,16 +11,51 @@ type RawInventory struct {
 	Devices []Device `yaml:"devices"`
 }
 
-func Load(path string) []Device {
+func Load(path string) ([]Device, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatalf("Error reading inventory file: %v", err)
+		return nil, fmt.Errorf("error reading inventory file: %w", err)
 	}
+	return parseNative(data, nil)
+}
 
+func parseNative(data, vaultPassword []byte) ([]Device, error) {
 	var inv RawInventory
 	if err := yaml.Unmarshal(data, &inv); err != nil {
-		log.Fatalf("Error parsing inventory YAML: %v", err)
+		return nil, fmt.Errorf("error parsing inventory YAML: %w", err)
 	}
 
-	return inv.Devices
+	for i, dev := range inv.Devices {
+		password, err := decryptValue(dev.Password, vaultPassword)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: password: %w", dev.Hostname, err)
+		}
+		inv.Devices[i].Password = password.(string)
+	}
+	return inv.Devices, nil
+}
+
+// Validate drops the devices that cannot be polled: devices without a
+// hostname or address, and every repeat of a hostname, since every series
+// and poller is keyed by hostname. It returns the remaining devices and one
+// error per dropped device, so a single broken entry does not cost the rest
+// of the inventory.
+func Validate(devices []Device) ([]Device, []error) {
+	var valid []Device
+	var errs []error
+	seen := make(map[string]bool, len(devices))
+	for _, dev := range devices {
+		switch {
+		case dev.Hostname == "":
+			errs = append(errs, fmt.Errorf("device with IP %q has no hostname", dev.IP))
+		case dev.IP == "":
+			errs = append(errs, fmt.Errorf("device %s has no IP address", dev.Hostname))
+		case seen[dev.Hostname]:
+			errs = append(errs, fmt.Errorf("duplicate device hostname %s", dev.Hostname))
+		default:
+			seen[dev.Hostname] = true
+			valid = append(valid, dev)
+		}
+	}
+	return valid, errs
 }
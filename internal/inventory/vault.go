@@ -0,0 +1,178 @@
+package inventory
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const vaultHeader = "$ANSIBLE_VAULT;"
+
+// IsVaultEncrypted reports whether data is an Ansible Vault payload, either a
+// whole encrypted file or the body of an inline !vault value.
+func IsVaultEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(vaultHeader))
+}
+
+// DecryptVault decrypts an Ansible Vault 1.1 or 1.2 payload with the AES256
+// cipher, the only one ansible-vault writes.
+func DecryptVault(data, password []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	header := strings.Split(strings.TrimSpace(lines[0]), ";")
+	if len(header) < 3 || header[0] != "$ANSIBLE_VAULT" {
+		return nil, errors.New("vault: missing $ANSIBLE_VAULT header")
+	}
+	if header[1] != "1.1" && header[1] != "1.2" {
+		return nil, fmt.Errorf("vault: unsupported format version %s", header[1])
+	}
+	if header[2] != "AES256" {
+		return nil, fmt.Errorf("vault: unsupported cipher %s", header[2])
+	}
+
+	var body strings.Builder
+	for _, l := range lines[1:] {
+		body.WriteString(strings.TrimSpace(l))
+	}
+	envelope, err := hex.DecodeString(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("vault: malformed payload: %w", err)
+	}
+	parts := strings.Split(string(envelope), "\n")
+	if len(parts) != 3 {
+		return nil, errors.New("vault: malformed payload")
+	}
+	var salt, mac, ciphertext []byte
+	for i, dst := range []*[]byte{&salt, &mac, &ciphertext} {
+		if *dst, err = hex.DecodeString(parts[i]); err != nil {
+			return nil, fmt.Errorf("vault: malformed payload: %w", err)
+		}
+	}
+
+	// 32 bytes of AES key, 32 bytes of HMAC key and a 16 byte CTR IV.
+	key := pbkdf2.Key(password, salt, 10000, 80, sha256.New)
+	aesKey, hmacKey, iv := key[:32], key[32:64], key[64:]
+
+	h := hmac.New(sha256.New, hmacKey)
+	h.Write(ciphertext)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, errors.New("vault: decryption failed (wrong password?)")
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
+
+	// Strip the PKCS#7 padding.
+	if len(plaintext) == 0 {
+		return nil, errors.New("vault: empty payload")
+	}
+	pad := int(plaintext[len(plaintext)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(plaintext) {
+		return nil, errors.New("vault: invalid padding")
+	}
+	return plaintext[:len(plaintext)-pad], nil
+}
+
+// ReadVaultPasswordFile reads a vault password the way ansible-vault does:
+// an executable file is run and its output used, any other file is read.
+// Trailing newlines are stripped.
+func ReadVaultPasswordFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if info.Mode()&0111 != 0 {
+		data, err = exec.Command(path).Output()
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault password from %s: %w", path, err)
+	}
+
+	password := bytes.TrimRight(data, "\r\n")
+	if len(password) == 0 {
+		return nil, fmt.Errorf("vault password file %s is empty", path)
+	}
+	return password, nil
+}
+
+// decryptFile returns data unchanged unless the whole file is vault-encrypted,
+// like a vars file written by "ansible-vault encrypt".
+func decryptFile(path string, data, password []byte) ([]byte, error) {
+	if !IsVaultEncrypted(data) {
+		return data, nil
+	}
+	if password == nil {
+		return nil, fmt.Errorf("%s is vault-encrypted but no vault password is configured", path)
+	}
+	plain, err := DecryptVault(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// decryptValues replaces every inline !vault value in vars, including in
+// nested maps and lists, with its plaintext.
+func decryptValues(vars map[string]interface{}, password []byte) error {
+	for k, v := range vars {
+		plain, err := decryptValue(v, password)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		vars[k] = plain
+	}
+	return nil
+}
+
+func decryptValue(v interface{}, password []byte) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if !IsVaultEncrypted([]byte(val)) {
+			return val, nil
+		}
+		if password == nil {
+			return nil, errors.New("value is vault-encrypted but no vault password is configured")
+		}
+		plain, err := DecryptVault([]byte(val), password)
+		if err != nil {
+			return nil, err
+		}
+		return string(plain), nil
+
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			plain, err := decryptValue(item, password)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = plain
+		}
+	case []interface{}:
+		for i, item := range val {
+			plain, err := decryptValue(item, password)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = plain
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+// The fixtures below use the password in vaultPassword. ansible-vault was not
+// available when they were written, so they were produced by a separate
+// stdlib-only Python port of ansible.parsing.vault.VaultAES256.encrypt
+// (PBKDF2-SHA256 with 10000 rounds, AES-256-CTR, HMAC-SHA256 over the
+// ciphertext, PKCS#7 padding), checked against the FIPS-197 and SP 800-38A
+// AES-256 vectors. They are what "ansible-vault encrypt_string" prints,
+// minus the "!vault |" tag.
+const vaultPassword = "netmetrics"
+
+// vault11 is "n3tw0rk-1.1".
+const vault11 = `$ANSIBLE_VAULT;1.1;AES256
+63323762616266336631366339326665383430656338306430313836333364373461303630363133
+3733366435343862656635363330303061393864623565640a623837363734383131326631313136
+34623662363666373665626238373935313437383561323264343237656638336363386338343832
+3861333631653336620a396564306536386432393436623463376564366363363735393438303837
+3636`
+
+// vault12 is "n3tw0rk-1.2", encrypted with the vault ID "prod".
+const vault12 = `$ANSIBLE_VAULT;1.2;AES256;prod
+65626565653966393330643033623432336635353334373864396134636238373731633133626230
+6635353932346533633437613234343065356363333334300a666131383431623038303538366566
+37373232303136653763346334313262616530393532366439356364396336393562343937616438
+3732393534666365620a353663343665646236636137653131323836666138356663353066333066
+3330`
+
+// vaultFile is a whole vars file, "ansible_password: from-vault-file\n", as
+// written by "ansible-vault encrypt".
+const vaultFile = `$ANSIBLE_VAULT;1.1;AES256
+39303034303031313763323336623965613965396134343732336430613633333166663332373335
+6438633561643334313736663139303339343564396463330a333638356563646530663865643239
+61613039653663346666396532346238666135313364326265326163633863346234653034366261
+3732376265393433350a303531333163633937323565323232366163316466623664393839343839
+39663666663863316536336264383063653632316331656531636264623564313334373862323063
+3232613839636266613039663839343137373438633763643330`
+
+func TestDecryptVault(t *testing.T) {
+	// Flip one hex digit of the ciphertext so the HMAC no longer matches.
+	tampered := vault11[:len(vault11)-1] + "7"
+
+	tests := []struct {
+		name     string
+		data     string
+		password string
+		want     string
+		wantErr  string
+	}{
+		{name: "1.1", data: vault11, password: vaultPassword, want: "n3tw0rk-1.1"},
+		{name: "1.2 with vault ID", data: vault12, password: vaultPassword, want: "n3tw0rk-1.2"},
+		{name: "indented like an inline !vault value", data: indent(vault11, "          "), password: vaultPassword, want: "n3tw0rk-1.1"},
+		{name: "whole file", data: vaultFile + "\n", password: vaultPassword, want: "ansible_password: from-vault-file\n"},
+		{name: "wrong password", data: vault11, password: "not-it", wantErr: "wrong password"},
+		{name: "tampered ciphertext", data: tampered, password: vaultPassword, wantErr: "wrong password"},
+		{name: "no header", data: "secret", password: vaultPassword, wantErr: "missing $ANSIBLE_VAULT header"},
+		{name: "unsupported version", data: strings.Replace(vault11, "1.1", "1.0", 1), password: vaultPassword, wantErr: "unsupported format version 1.0"},
+		{name: "unsupported cipher", data: strings.Replace(vault11, "AES256", "AES", 1), password: vaultPassword, wantErr: "unsupported cipher AES"},
+		{name: "body not hex", data: "$ANSIBLE_VAULT;1.1;AES256\nzz", password: vaultPassword, wantErr: "malformed payload"},
+		{name: "envelope missing parts", data: "$ANSIBLE_VAULT;1.1;AES256\n61620a6364", password: vaultPassword, wantErr: "malformed payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptVault([]byte(tt.data), []byte(tt.password))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecryptVault() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecryptVault() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DecryptVault() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsVaultEncrypted(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{vault11, true},
+		{"\n  " + vault12, true},
+		{"ansible_password: plain", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsVaultEncrypted([]byte(tt.data)); got != tt.want {
+			t.Errorf("IsVaultEncrypted(%.20q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+// indent prefixes every line of s, the way a !vault block sits in YAML.
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
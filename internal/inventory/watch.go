@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// Watch polls path every interval and calls onChange whenever its content,
+// or that of its group_vars/ and host_vars/ directories, changes. Polling
+// the content instead of using inotify survives editors that replace the
+// file and config-map volumes that swap symlinks. Watch returns when ctx is
+// cancelled.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last := fingerprint(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sum := fingerprint(path)
+		if sum == last {
+			continue
+		}
+		last = sum
+		onChange()
+	}
+}
+
+// fingerprint hashes the file content together with the group_vars/ and
+// host_vars/ directories next to it. An unreadable inventory hashes to the
+// zero value, so it counts as a change once it is readable again.
+func fingerprint(path string) [sha256.Size]byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	h := sha256.New()
+	h.Write(data)
+
+	for _, dir := range varsDirs {
+		root := filepath.Join(filepath.Dir(path), dir)
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			io.WriteString(h, p)
+			if data, err := ioutil.ReadFile(p); err == nil {
+				h.Write(data)
+			}
+			return nil
+		})
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Exporter-wide families. They describe the exporter itself rather than a
+// device, so they live outside Set and are only exposed on /metrics.
+var (
+	InventoryDevices = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netmetrics_inventory_devices",
+			Help: "Number of devices in the active inventory.",
+		},
+	)
+
+	InventoryReloadSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netmetrics_inventory_last_reload_successful",
+			Help: "Whether the last inventory reload attempt was successful (1) or not (0).",
+		},
+	)
+
+	InventoryReloadTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netmetrics_inventory_last_reload_success_timestamp_seconds",
+			Help: "Unix time of the last successful inventory (re)load.",
+		},
+	)
+
+	InventoryInvalidDevices = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "netmetrics_inventory_invalid_devices",
+			Help: "Number of inventory entries skipped by the last successful reload because they cannot be polled.",
+		},
+	)
+
+	InventoryUnsupportedDevices = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "netmetrics_inventory_unsupported_device",
+			Help: "Inventory devices that no registered collector can poll (always 1). They are not polled.",
+		},
+		[]string{"hostname", "vendor", "protocol"},
+	)
+)
+
+func registerExporter(r prometheus.Registerer) {
+	r.MustRegister(InventoryDevices, InventoryReloadSuccess, InventoryReloadTimestamp, InventoryInvalidDevices, InventoryUnsupportedDevices)
+}
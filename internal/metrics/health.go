@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SectionAll is the section label used for the outcome of a whole device
+// poll, as opposed to a single block (interfaces, BGP, ...) inside it.
+const SectionAll = "all"
+
+// health holds the self-observability families. Unlike the device families
+// they are not expired at the end of a cycle: a failing section must keep its
+// last success timestamp and error count.
+type health struct {
+	DeviceUp             *prometheus.GaugeVec
+	CollectDuration      *prometheus.GaugeVec
+	CollectErrors        *prometheus.CounterVec
+	LastSuccessTimestamp *prometheus.GaugeVec
+}
+
+func newHealth() health {
+	return health{
+		DeviceUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "netmetrics_device_up",
+				Help: "Whether the last poll of the device succeeded (1) or failed (0).",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		CollectDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "netmetrics_collect_duration_seconds",
+				Help: "Duration of the last collection per device and section (section=\"all\" for the whole poll).",
+			},
+			[]string{"hostname", "section"},
+		),
+		CollectErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "netmetrics_collect_errors_total",
+				Help: "Number of failed collections per device and section (section=\"all\" for the whole poll).",
+			},
+			[]string{"hostname", "section"},
+		),
+		LastSuccessTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "netmetrics_last_success_timestamp_seconds",
+				Help: "Unix time of the last successful collection per device and section.",
+			},
+			[]string{"hostname", "section"},
+		),
+	}
+}
+
+func (h health) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		h.DeviceUp,
+		h.CollectDuration,
+		h.CollectErrors,
+		h.LastSuccessTimestamp,
+	}
+}
+
+func (h health) deleteDevice(hostname string) {
+	labels := prometheus.Labels{"hostname": hostname}
+	h.DeviceUp.DeletePartialMatch(labels)
+	h.CollectDuration.DeletePartialMatch(labels)
+	h.CollectErrors.DeletePartialMatch(labels)
+	h.LastSuccessTimestamp.DeletePartialMatch(labels)
+}
+
+// ObserveSection records the outcome of one collection section, such as the
+// interface or BGP block of a collector, that started at start.
+func (s *Set) ObserveSection(hostname, section string, start time.Time, err error) {
+	s.CollectDuration.WithLabelValues(hostname, section).Set(time.Since(start).Seconds())
+
+	errs := s.CollectErrors.WithLabelValues(hostname, section)
+	if err != nil {
+		errs.Inc()
+		return
+	}
+	// Export the counter at zero so rate() works before the first failure.
+	errs.Add(0)
+	s.LastSuccessTimestamp.WithLabelValues(hostname, section).SetToCurrentTime()
+}
+
+// ObserveDevice records the outcome of a whole device poll that started at
+// start.
+func (s *Set) ObserveDevice(hostname, vendor string, start time.Time, err error) {
+	up := 1.0
+	if err != nil {
+		up = 0
+	}
+	s.DeviceUp.WithLabelValues(hostname, vendor).Set(up)
+	s.ObserveSection(hostname, SectionAll, start, err)
+}
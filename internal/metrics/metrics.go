@@ -1,128 +1,881 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
-
-var (
-	InterfaceUp = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_interface_up",
-			Help: "Indicates whether the interface is up (1) or down (0).",
-		},
-		[]string{"hostname", "interface", "vendor"},
-	)
-
-	BGPPeers = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_bgp_neighbors_total",
-			Help: "Total number of BGP peers per device.",
-		},
-		[]string{"hostname", "vendor"},
-	)
-
-	InterfaceSpeedMbps = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_interface_speed_mbps",
-			Help: "Interface speed in Mbps",
-		},
-		[]string{"hostname", "interface", "vendor"},
-	)
-	InterfaceDuplex = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_interface_duplex",
-			Help: "Interface duplex mode (label: duplex=duplexFull|duplexHalf|unknown)",
-		},
-		[]string{"hostname", "interface", "vendor", "duplex"},
-	)
-	DeviceUptimeSeconds = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_device_uptime_seconds",
-			Help: "Device uptime in seconds",
-		},
-		[]string{"hostname"},
-	)
-
-	DeviceInfo = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_device_info",
-			Help: "Device model and OS version",
-		},
-		[]string{"hostname", "model", "version"},
-	)
-	OSPFNeighbors = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_ospf_neighbors_total",
-			Help: "Number of OSPF neighbors",
-		},
-		[]string{"hostname", "vendor"},
-	)
-	InterfaceInputErrors = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_interface_input_errors_total",
-			Help: "Input errors per interface",
-		},
-		[]string{"hostname", "interface"},
-	)
-
-	InterfaceOutputErrors = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_interface_output_errors_total",
-			Help: "Output errors per interface",
-		},
-		[]string{"hostname", "interface"},
-	)
-
-	LLDPNeighbors = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_lldp_neighbors_total",
-			Help: "Number of LLDP neighbors",
-		},
-		[]string{"hostname", "vendor"},
-	)
-
-	DeviceMemoryTotal = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "net_device_memory_total_mb",
-			Help: "Total memory (in MB)",
-		},
-		[]string{"hostname"},
-	)
-
-	DeviceMemoryFree = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "net_device_memory_free_mb",
-			Help: "Free memory (in MB)",
-		},
-		[]string{"hostname"},
-	)
-	CPUUsage = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_cpu_usage_percent",
-			Help: "CPU usage percent reported by the device",
-		},
-		[]string{"hostname", "vendor"},
-	)
-	MemoryUsage = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "netmetrics_memory_usage_percent",
-			Help: "Memory usage percent calculated from total/used",
-		},
-		[]string{"hostname", "vendor"},
-	)
+import (
+	"math"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Set holds one instance of every device metric family. Background polling
+// writes into Default; the /probe endpoint builds a fresh Set per request so
+// only the probed device is returned.
+type Set struct {
+	health
+	tracker *tracker
+
+	InterfaceUp           *GaugeVec
+	BGPPeers              *GaugeVec
+	InterfaceSpeedMbps    *GaugeVec
+	InterfaceDuplex       *GaugeVec
+	DeviceUptimeSeconds   *GaugeVec
+	DeviceInfo            *GaugeVec
+	OSPFNeighbors         *GaugeVec
+	ISISAdjacencies       *GaugeVec
+	InterfaceInputErrors  *GaugeVec
+	InterfaceOutputErrors *GaugeVec
+
+	// Interface traffic counters, see SetInterfaceCounters
+	InterfaceInOctets            *CounterVec
+	InterfaceOutOctets           *CounterVec
+	InterfaceInPackets           *CounterVec
+	InterfaceOutPackets          *CounterVec
+	InterfaceInUnicastPackets    *CounterVec
+	InterfaceOutUnicastPackets   *CounterVec
+	InterfaceInMulticastPackets  *CounterVec
+	InterfaceOutMulticastPackets *CounterVec
+	InterfaceInBroadcastPackets  *CounterVec
+	InterfaceOutBroadcastPackets *CounterVec
+	InterfaceInDiscards          *CounterVec
+	InterfaceOutDiscards         *CounterVec
+
+	// BGP sessions, see SetBGPPeer
+	BGPPeerState              *GaugeVec
+	BGPPeerEstablishedSeconds *GaugeVec
+	BGPPeerFlaps              *CounterVec
+	BGPPeerPrefixesReceived   *GaugeVec
+	BGPPeerPrefixesAccepted   *GaugeVec
+	BGPPeerPrefixesAdvertised *GaugeVec
+
+	// OSPF adjacencies, see SetOSPFNeighbor
+	OSPFNeighborState         *GaugeVec
+	OSPFNeighborUptimeSeconds *GaugeVec
+
+	// IS-IS adjacencies, see SetISISAdjacency
+	ISISAdjacencyState           *GaugeVec
+	ISISAdjacencyHoldTimeSeconds *GaugeVec
+	ISISAdjacencyUptimeSeconds   *GaugeVec
+	ISISLSPDatabaseLSPs          *GaugeVec
+
+	// Optical transceivers, see SetTransceiver
+	TransceiverInfo               *GaugeVec
+	TransceiverRxPowerDBm         *GaugeVec
+	TransceiverTxPowerDBm         *GaugeVec
+	TransceiverBiasMilliamperes   *GaugeVec
+	TransceiverTemperatureCelsius *GaugeVec
+	TransceiverVoltageVolts       *GaugeVec
+	TransceiverThreshold          *GaugeVec
+
+	LLDPNeighbors *GaugeVec
+
+	// CPU and memory; memory is written through SetMemory
+	CPUUsage         *GaugeVec
+	CPUUsageAverage  *GaugeVec
+	CPUCoreUsage     *GaugeVec
+	CPULoadAverage   *GaugeVec
+	MemoryUsage      *GaugeVec
+	MemoryTotalBytes *GaugeVec
+	MemoryUsedBytes  *GaugeVec
+	MemoryFreeBytes  *GaugeVec
+
+	// Chassis environment
+	TemperatureCelsius   *GaugeVec
+	TemperatureThreshold *GaugeVec
+	FanOK                *GaugeVec
+	FanSpeedPercent      *GaugeVec
+	FanSpeedRPM          *GaugeVec
+	PSUOK                *GaugeVec
+	PSUInputPowerWatts   *GaugeVec
+	PSUOutputPowerWatts  *GaugeVec
+	ModuleUp             *GaugeVec
+}
+
+// Default is the Set exposed on /metrics.
+var Default = NewSet()
+
+// NewSet returns a Set with freshly created, unregistered metric vectors.
+func NewSet() *Set {
+	t := newTracker()
+	return &Set{
+		health:  newHealth(),
+		tracker: t,
+
+		InterfaceUp: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_interface_up",
+				Help: "Indicates whether the interface is up (1) or down (0).",
+			},
+			[]string{"hostname", "interface", "vendor"},
+		),
+
+		BGPPeers: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_neighbors_total",
+				Help: "Total number of BGP peers per device.",
+			},
+			[]string{"hostname", "vendor"},
+		),
+
+		InterfaceSpeedMbps: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_interface_speed_mbps",
+				Help: "Interface speed in Mbps",
+			},
+			[]string{"hostname", "interface", "vendor"},
+		),
+		InterfaceDuplex: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_interface_duplex",
+				Help: "Interface duplex mode (label: duplex=duplexFull|duplexHalf|unknown)",
+			},
+			[]string{"hostname", "interface", "vendor", "duplex"},
+		),
+		DeviceUptimeSeconds: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_device_uptime_seconds",
+				Help: "Device uptime in seconds",
+			},
+			[]string{"hostname"},
+		),
+
+		DeviceInfo: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_device_info",
+				Help: "Device model and OS version",
+			},
+			[]string{"hostname", "model", "version"},
+		),
+		OSPFNeighbors: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_ospf_neighbors_total",
+				Help: "Number of OSPF neighbors",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		ISISAdjacencies: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_isis_adjacencies_total",
+				Help: "Total number of IS-IS adjacencies per device.",
+			},
+			[]string{"hostname", "vendor"},
+		),
+
+		InterfaceInputErrors: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_interface_input_errors_total",
+				Help: "Input errors per interface",
+			},
+			[]string{"hostname", "interface"},
+		),
+
+		InterfaceOutputErrors: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_interface_output_errors_total",
+				Help: "Output errors per interface",
+			},
+			[]string{"hostname", "interface"},
+		),
+
+		InterfaceInOctets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_octets_total",
+				Help: "Octets received on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutOctets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_octets_total",
+				Help: "Octets transmitted on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceInPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_packets_total",
+				Help: "Packets received on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_packets_total",
+				Help: "Packets transmitted on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceInUnicastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_unicast_packets_total",
+				Help: "Unicast packets received on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutUnicastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_unicast_packets_total",
+				Help: "Unicast packets transmitted on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceInMulticastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_multicast_packets_total",
+				Help: "Multicast packets received on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutMulticastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_multicast_packets_total",
+				Help: "Multicast packets transmitted on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceInBroadcastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_broadcast_packets_total",
+				Help: "Broadcast packets received on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutBroadcastPackets: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_broadcast_packets_total",
+				Help: "Broadcast packets transmitted on the interface.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceInDiscards: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_in_discards_total",
+				Help: "Inbound packets discarded without an error.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		InterfaceOutDiscards: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_interface_out_discards_total",
+				Help: "Outbound packets discarded without an error.",
+			},
+			[]string{"hostname", "interface"},
+		),
+
+		BGPPeerState: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_peer_state",
+				Help: "BGP session state as in BGP4-MIB: 1 idle, 2 connect, 3 active, 4 opensent, 5 openconfirm, 6 established, 0 unknown.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf"},
+		),
+		BGPPeerEstablishedSeconds: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_peer_established_seconds",
+				Help: "Time since the BGP session was established; absent while it is down.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf"},
+		),
+		BGPPeerFlaps: newCounterVec(t,
+			prometheus.CounterOpts{
+				Name: "netmetrics_bgp_peer_flaps_total",
+				Help: "Number of times the BGP session reached the established state.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf"},
+		),
+		BGPPeerPrefixesReceived: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_peer_prefixes_received",
+				Help: "Prefixes received from the BGP peer per address family.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf", "afi_safi"},
+		),
+		BGPPeerPrefixesAccepted: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_peer_prefixes_accepted",
+				Help: "Prefixes received from the BGP peer that passed inbound policy, per address family.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf", "afi_safi"},
+		),
+		BGPPeerPrefixesAdvertised: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_bgp_peer_prefixes_advertised",
+				Help: "Prefixes advertised to the BGP peer per address family.",
+			},
+			[]string{"hostname", "peer", "remote_as", "vrf", "afi_safi"},
+		),
+
+		OSPFNeighborState: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_ospf_neighbor_state",
+				Help: "OSPF adjacency state as in OSPF-MIB: 1 down, 2 attempt, 3 init, 4 two-way, 5 exchange-start, 6 exchange, 7 loading, 8 full, 0 unknown.",
+			},
+			[]string{"hostname", "router_id", "neighbor", "area", "interface", "vrf", "instance"},
+		),
+		OSPFNeighborUptimeSeconds: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_ospf_neighbor_uptime_seconds",
+				Help: "Time since the OSPF adjacency became full; absent while it is not.",
+			},
+			[]string{"hostname", "router_id", "neighbor", "area", "interface", "vrf", "instance"},
+		),
+
+		ISISAdjacencyState: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_isis_adjacency_state",
+				Help: "IS-IS adjacency state as in ISIS-MIB: 1 down, 2 initializing, 3 up, 4 failed, 0 unknown.",
+			},
+			[]string{"hostname", "instance", "system_id", "interface", "level"},
+		),
+		ISISAdjacencyHoldTimeSeconds: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_isis_adjacency_hold_time_seconds",
+				Help: "Hold time left before the IS-IS adjacency expires.",
+			},
+			[]string{"hostname", "instance", "system_id", "interface", "level"},
+		),
+		ISISAdjacencyUptimeSeconds: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_isis_adjacency_uptime_seconds",
+				Help: "Time since the IS-IS adjacency came up; absent while it is not up.",
+			},
+			[]string{"hostname", "instance", "system_id", "interface", "level"},
+		),
+		ISISLSPDatabaseLSPs: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_isis_lsp_database_lsps",
+				Help: "Number of LSPs in the IS-IS link-state database per level.",
+			},
+			[]string{"hostname", "instance", "level"},
+		),
+
+		TransceiverInfo: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_info",
+				Help: "Transceiver manufacturer, part number and serial number; always 1.",
+			},
+			[]string{"hostname", "interface", "manufacturer", "part_number", "serial"},
+		),
+		TransceiverRxPowerDBm: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_rx_power_dbm",
+				Help: "Received optical power per lane in dBm.",
+			},
+			[]string{"hostname", "interface", "lane"},
+		),
+		TransceiverTxPowerDBm: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_tx_power_dbm",
+				Help: "Transmitted optical power per lane in dBm.",
+			},
+			[]string{"hostname", "interface", "lane"},
+		),
+		TransceiverBiasMilliamperes: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_bias_current_milliamperes",
+				Help: "Laser bias current per lane in mA.",
+			},
+			[]string{"hostname", "interface", "lane"},
+		),
+		TransceiverTemperatureCelsius: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_temperature_celsius",
+				Help: "Transceiver temperature in degrees Celsius.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		TransceiverVoltageVolts: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_voltage_volts",
+				Help: "Transceiver supply voltage in volts.",
+			},
+			[]string{"hostname", "interface"},
+		),
+		TransceiverThreshold: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_transceiver_threshold",
+				Help: "Vendor alarm and warning thresholds of a transceiver reading, in the unit of that reading " +
+					"(sensor=rx_power|tx_power|bias_current|temperature|voltage, level=high_alarm|high_warning|low_warning|low_alarm).",
+			},
+			[]string{"hostname", "interface", "sensor", "level"},
+		),
+
+		LLDPNeighbors: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_lldp_neighbors_total",
+				Help: "Number of LLDP neighbors",
+			},
+			[]string{"hostname", "vendor"},
+		),
+
+		CPUUsage: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_cpu_usage_percent",
+				Help: "CPU usage percent reported by the device",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		CPUUsageAverage: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_cpu_usage_average_percent",
+				Help: "CPU usage percent averaged by the device over a window (window=1m|5m|15m).",
+			},
+			[]string{"hostname", "vendor", "window"},
+		),
+		CPUCoreUsage: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_cpu_core_usage_percent",
+				Help: "Usage percent of a single CPU core.",
+			},
+			[]string{"hostname", "vendor", "core"},
+		),
+		CPULoadAverage: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_cpu_load_average",
+				Help: "Load average of the device's operating system (window=1m|5m|15m).",
+			},
+			[]string{"hostname", "vendor", "window"},
+		),
+		MemoryUsage: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_memory_usage_percent",
+				Help: "Memory usage percent calculated from total/used",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		MemoryTotalBytes: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_memory_total_bytes",
+				Help: "Total memory in bytes.",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		MemoryUsedBytes: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_memory_used_bytes",
+				Help: "Used memory in bytes.",
+			},
+			[]string{"hostname", "vendor"},
+		),
+		MemoryFreeBytes: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_memory_free_bytes",
+				Help: "Free memory in bytes.",
+			},
+			[]string{"hostname", "vendor"},
+		),
+
+		TemperatureCelsius: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_temperature_celsius",
+				Help: "Temperature reported by a chassis sensor in degrees Celsius.",
+			},
+			[]string{"hostname", "sensor"},
+		),
+		TemperatureThreshold: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_temperature_threshold_celsius",
+				Help: "Alarm threshold of a chassis temperature sensor (level=warning|critical).",
+			},
+			[]string{"hostname", "sensor", "level"},
+		),
+		FanOK: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_fan_ok",
+				Help: "Whether a fan reports a healthy status (1) or not (0).",
+			},
+			[]string{"hostname", "fan"},
+		),
+		FanSpeedPercent: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_fan_speed_percent",
+				Help: "Fan speed in percent of its maximum.",
+			},
+			[]string{"hostname", "fan"},
+		),
+		FanSpeedRPM: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_fan_speed_rpm",
+				Help: "Fan speed in revolutions per minute.",
+			},
+			[]string{"hostname", "fan"},
+		),
+		PSUOK: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_psu_ok",
+				Help: "Whether a power supply reports a healthy status (1) or not (0).",
+			},
+			[]string{"hostname", "psu"},
+		),
+		PSUInputPowerWatts: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_psu_input_power_watts",
+				Help: "Power drawn by a power supply in watts.",
+			},
+			[]string{"hostname", "psu"},
+		),
+		PSUOutputPowerWatts: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_psu_output_power_watts",
+				Help: "Power delivered by a power supply in watts.",
+			},
+			[]string{"hostname", "psu"},
+		),
+		ModuleUp: newGaugeVec(t,
+			prometheus.GaugeOpts{
+				Name: "netmetrics_module_up",
+				Help: "Whether a linecard or module is operationally up (1) or not (0).",
+			},
+			[]string{"hostname", "module"},
+		),
+	}
+}
+
+// InterfaceCounters are the traffic counters of one interface, as totals
+// since the device or its counters were last reset. A zero InPackets or
+// OutPackets is filled in as the sum of unicast, multicast and broadcast.
+type InterfaceCounters struct {
+	InOctets, OutOctets                     float64
+	InPackets, OutPackets                   float64
+	InUnicastPackets, OutUnicastPackets     float64
+	InMulticastPackets, OutMulticastPackets float64
+	InBroadcastPackets, OutBroadcastPackets float64
+	InDiscards, OutDiscards                 float64
+}
+
+// SetInterfaceCounters writes every traffic counter of one interface.
+func (s *Set) SetInterfaceCounters(hostname, iface string, c InterfaceCounters) {
+	if c.InPackets == 0 {
+		c.InPackets = c.InUnicastPackets + c.InMulticastPackets + c.InBroadcastPackets
+	}
+	if c.OutPackets == 0 {
+		c.OutPackets = c.OutUnicastPackets + c.OutMulticastPackets + c.OutBroadcastPackets
+	}
+	s.InterfaceInOctets.WithLabelValues(hostname, iface).Set(c.InOctets)
+	s.InterfaceOutOctets.WithLabelValues(hostname, iface).Set(c.OutOctets)
+	s.InterfaceInPackets.WithLabelValues(hostname, iface).Set(c.InPackets)
+	s.InterfaceOutPackets.WithLabelValues(hostname, iface).Set(c.OutPackets)
+	s.InterfaceInUnicastPackets.WithLabelValues(hostname, iface).Set(c.InUnicastPackets)
+	s.InterfaceOutUnicastPackets.WithLabelValues(hostname, iface).Set(c.OutUnicastPackets)
+	s.InterfaceInMulticastPackets.WithLabelValues(hostname, iface).Set(c.InMulticastPackets)
+	s.InterfaceOutMulticastPackets.WithLabelValues(hostname, iface).Set(c.OutMulticastPackets)
+	s.InterfaceInBroadcastPackets.WithLabelValues(hostname, iface).Set(c.InBroadcastPackets)
+	s.InterfaceOutBroadcastPackets.WithLabelValues(hostname, iface).Set(c.OutBroadcastPackets)
+	s.InterfaceInDiscards.WithLabelValues(hostname, iface).Set(c.InDiscards)
+	s.InterfaceOutDiscards.WithLabelValues(hostname, iface).Set(c.OutDiscards)
+}
+
+// BGPPeer is the state of one BGP session. Numbers a device does not
+// report are negative and left out.
+type BGPPeer struct {
+	VRF      string
+	Address  string
+	RemoteAS string
+
+	// State is the FSM state name in any case, e.g. "Established".
+	State              string
+	EstablishedSeconds float64
+	Flaps              float64
+
+	Families []BGPFamily
+}
+
+// BGPFamily holds the prefix counts of one address family of a session,
+// such as "ipv4-unicast".
+type BGPFamily struct {
+	AFISAFI    string
+	Received   float64
+	Accepted   float64
+	Advertised float64
+}
+
+// bgpStates numbers the FSM states like bgpPeerState in BGP4-MIB.
+var bgpStates = map[string]float64{
+	"idle":        1,
+	"connect":     2,
+	"active":      3,
+	"opensent":    4,
+	"openconfirm": 5,
+	"established": 6,
+}
+
+// SetBGPPeer writes every metric of one BGP session.
+func (s *Set) SetBGPPeer(hostname string, p BGPPeer) {
+	state := bgpStates[strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(p.State))]
+	s.BGPPeerState.WithLabelValues(hostname, p.Address, p.RemoteAS, p.VRF).Set(state)
+	if state != bgpStates["established"] {
+		s.BGPPeerEstablishedSeconds.DeleteLabelValues(hostname, p.Address, p.RemoteAS, p.VRF)
+	} else if p.EstablishedSeconds >= 0 {
+		s.BGPPeerEstablishedSeconds.WithLabelValues(hostname, p.Address, p.RemoteAS, p.VRF).Set(p.EstablishedSeconds)
+	}
+	if p.Flaps >= 0 {
+		s.BGPPeerFlaps.WithLabelValues(hostname, p.Address, p.RemoteAS, p.VRF).Set(p.Flaps)
+	}
+	for _, f := range p.Families {
+		for _, c := range []struct {
+			vec   *GaugeVec
+			value float64
+		}{
+			{s.BGPPeerPrefixesReceived, f.Received},
+			{s.BGPPeerPrefixesAccepted, f.Accepted},
+			{s.BGPPeerPrefixesAdvertised, f.Advertised},
+		} {
+			if c.value >= 0 {
+				c.vec.WithLabelValues(hostname, p.Address, p.RemoteAS, p.VRF, f.AFISAFI).Set(c.value)
+			}
+		}
+	}
+}
+
+// OSPFNeighbor is one OSPF adjacency.
+type OSPFNeighbor struct {
+	VRF       string
+	Instance  string
+	Area      string
+	Interface string
+	RouterID  string
+	Address   string
+
+	// State is the neighbor state name in any case or spelling, e.g.
+	// "FULL", "two-way" or "ospf-nbr-exchange-start".
+	State string
+
+	// UptimeSeconds is negative when the device does not report it.
+	UptimeSeconds float64
+}
+
+// ospfStates numbers the neighbor states like ospfNbrState in OSPF-MIB.
+var ospfStates = map[string]float64{
+	"down":          1,
+	"attempt":       2,
+	"init":          3,
+	"twoway":        4,
+	"2way":          4,
+	"2ways":         4,
+	"exchangestart": 5,
+	"exstart":       5,
+	"exchange":      6,
+	"loading":       7,
+	"full":          8,
+}
+
+// SetOSPFNeighbor writes the metrics of one OSPF adjacency.
+func (s *Set) SetOSPFNeighbor(hostname string, n OSPFNeighbor) {
+	name := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(n.State))
+	state := ospfStates[strings.TrimPrefix(name, "ospfnbr")]
+	labels := []string{hostname, n.RouterID, n.Address, n.Area, n.Interface, n.VRF, n.Instance}
+	s.OSPFNeighborState.WithLabelValues(labels...).Set(state)
+	if state != ospfStates["full"] {
+		s.OSPFNeighborUptimeSeconds.DeleteLabelValues(labels...)
+	} else if n.UptimeSeconds >= 0 {
+		s.OSPFNeighborUptimeSeconds.WithLabelValues(labels...).Set(n.UptimeSeconds)
+	}
+}
+
+// ISISAdjacency is one IS-IS adjacency. Numbers a device does not report
+// are negative and left out.
+type ISISAdjacency struct {
+	Instance  string
+	SystemID  string
+	Interface string
+
+	// Level is written in any of the usual spellings: "2", "L2",
+	// "level-2", "L1L2", "isis-level-1-2", ...
+	Level string
+
+	// State is the adjacency state name, e.g. "up", "INIT" or "isis-adj-up".
+	State string
+
+	HoldTimeSeconds float64
+	UptimeSeconds   float64
+}
+
+// isisStates numbers the adjacency states like isisISAdjState in ISIS-MIB.
+var isisStates = map[string]float64{
+	"down":         1,
+	"init":         2,
+	"initializing": 2,
+	"up":           3,
+	"failed":       4,
+}
+
+// SetISISAdjacency writes the metrics of one IS-IS adjacency.
+func (s *Set) SetISISAdjacency(hostname string, a ISISAdjacency) {
+	name := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(a.State))
+	state := isisStates[strings.TrimPrefix(name, "isisadj")]
+	labels := []string{hostname, a.Instance, a.SystemID, a.Interface, ISISLevel(a.Level)}
+	s.ISISAdjacencyState.WithLabelValues(labels...).Set(state)
+	if a.HoldTimeSeconds >= 0 {
+		s.ISISAdjacencyHoldTimeSeconds.WithLabelValues(labels...).Set(a.HoldTimeSeconds)
+	}
+	if state != isisStates["up"] {
+		s.ISISAdjacencyUptimeSeconds.DeleteLabelValues(labels...)
+	} else if a.UptimeSeconds >= 0 {
+		s.ISISAdjacencyUptimeSeconds.WithLabelValues(labels...).Set(a.UptimeSeconds)
+	}
+}
+
+// ISISLevel spells an IS-IS level the same way for every vendor:
+// "level-1", "level-2" or "level-1-2".
+func ISISLevel(level string) string {
+	l1, l2 := strings.Contains(level, "1"), strings.Contains(level, "2")
+	switch {
+	case l1 && l2:
+		return "level-1-2"
+	case l1:
+		return "level-1"
+	case l2:
+		return "level-2"
+	}
+	return "unknown"
+}
+
+// Transceiver holds the digital optical monitoring readings of one
+// transceiver. Readings a device does not report are NaN and left out.
+type Transceiver struct {
+	Interface    string
+	Manufacturer string
+	PartNumber   string
+	Serial       string
+
+	TemperatureCelsius float64
+	VoltageVolts       float64
+	Lanes              []TransceiverLane
+	Thresholds         []TransceiverThreshold
+}
+
+// TransceiverLane holds the readings of one optical lane, numbered from 1.
+type TransceiverLane struct {
+	Lane             string
+	RxPowerDBm       float64
+	TxPowerDBm       float64
+	BiasMilliamperes float64
+}
+
+// TransceiverThreshold is one vendor threshold. Sensor and Level take the
+// values listed in the help of netmetrics_transceiver_threshold.
+type TransceiverThreshold struct {
+	Sensor string
+	Level  string
+	Value  float64
+}
+
+// SetTransceiver writes every metric of one transceiver.
+func (s *Set) SetTransceiver(hostname string, t Transceiver) {
+	if t.PartNumber != "" || t.Serial != "" {
+		s.TransceiverInfo.WithLabelValues(hostname, t.Interface, t.Manufacturer, t.PartNumber, t.Serial).Set(1)
+	}
+	setReading(s.TransceiverTemperatureCelsius, t.TemperatureCelsius, hostname, t.Interface)
+	setReading(s.TransceiverVoltageVolts, t.VoltageVolts, hostname, t.Interface)
+	for _, l := range t.Lanes {
+		setReading(s.TransceiverRxPowerDBm, l.RxPowerDBm, hostname, t.Interface, l.Lane)
+		setReading(s.TransceiverTxPowerDBm, l.TxPowerDBm, hostname, t.Interface, l.Lane)
+		setReading(s.TransceiverBiasMilliamperes, l.BiasMilliamperes, hostname, t.Interface, l.Lane)
+	}
+	for _, th := range t.Thresholds {
+		setReading(s.TransceiverThreshold, th.Value, hostname, t.Interface, th.Sensor, th.Level)
+	}
+}
+
+// setReading sets a gauge unless v is NaN.
+func setReading(vec *GaugeVec, v float64, lvs ...string) {
+	if !math.IsNaN(v) {
+		vec.WithLabelValues(lvs...).Set(v)
+	}
+}
+
+// SetMemory writes the memory families from the total and used bytes.
+func (s *Set) SetMemory(hostname, vendor string, totalBytes, usedBytes float64) {
+	if totalBytes <= 0 {
+		return
+	}
+	s.MemoryTotalBytes.WithLabelValues(hostname, vendor).Set(totalBytes)
+	s.MemoryUsedBytes.WithLabelValues(hostname, vendor).Set(usedBytes)
+	s.MemoryFreeBytes.WithLabelValues(hostname, vendor).Set(totalBytes - usedBytes)
+	s.MemoryUsage.WithLabelValues(hostname, vendor).Set(usedBytes / totalBytes * 100)
+}
+
+// Collectors returns every metric vector in the Set.
+func (s *Set) Collectors() []prometheus.Collector {
+	cs := s.health.collectors()
+	for _, v := range s.vecs() {
+		cs = append(cs, v)
+	}
+	return cs
+}
+
+// vecs returns the per-device vectors that are subject to stale removal.
+func (s *Set) vecs() []trackedVec {
+	return []trackedVec{
+		s.InterfaceUp,
+		s.BGPPeers,
+		s.InterfaceSpeedMbps,
+		s.InterfaceDuplex,
+		s.DeviceUptimeSeconds,
+		s.DeviceInfo,
+		s.OSPFNeighbors,
+		s.ISISAdjacencies,
+		s.InterfaceInputErrors,
+		s.InterfaceOutputErrors,
+		s.InterfaceInOctets,
+		s.InterfaceOutOctets,
+		s.InterfaceInPackets,
+		s.InterfaceOutPackets,
+		s.InterfaceInUnicastPackets,
+		s.InterfaceOutUnicastPackets,
+		s.InterfaceInMulticastPackets,
+		s.InterfaceOutMulticastPackets,
+		s.InterfaceInBroadcastPackets,
+		s.InterfaceOutBroadcastPackets,
+		s.InterfaceInDiscards,
+		s.InterfaceOutDiscards,
+		s.BGPPeerState,
+		s.BGPPeerEstablishedSeconds,
+		s.BGPPeerFlaps,
+		s.BGPPeerPrefixesReceived,
+		s.BGPPeerPrefixesAccepted,
+		s.BGPPeerPrefixesAdvertised,
+		s.OSPFNeighborState,
+		s.OSPFNeighborUptimeSeconds,
+		s.ISISAdjacencyState,
+		s.ISISAdjacencyHoldTimeSeconds,
+		s.ISISAdjacencyUptimeSeconds,
+		s.ISISLSPDatabaseLSPs,
+		s.TransceiverInfo,
+		s.TransceiverRxPowerDBm,
+		s.TransceiverTxPowerDBm,
+		s.TransceiverBiasMilliamperes,
+		s.TransceiverTemperatureCelsius,
+		s.TransceiverVoltageVolts,
+		s.TransceiverThreshold,
+		s.LLDPNeighbors,
+		s.CPUUsage,
+		s.CPUUsageAverage,
+		s.CPUCoreUsage,
+		s.CPULoadAverage,
+		s.MemoryUsage,
+		s.MemoryTotalBytes,
+		s.MemoryUsedBytes,
+		s.MemoryFreeBytes,
+		s.TemperatureCelsius,
+		s.TemperatureThreshold,
+		s.FanOK,
+		s.FanSpeedPercent,
+		s.FanSpeedRPM,
+		s.PSUOK,
+		s.PSUInputPowerWatts,
+		s.PSUOutputPowerWatts,
+		s.ModuleUp,
+	}
+}
+
+// MustRegister registers every metric vector in the Set with r.
+func (s *Set) MustRegister(r prometheus.Registerer) {
+	r.MustRegister(s.Collectors()...)
+}
+
+// Register registers the Default set and the exporter-wide families with the
+// global Prometheus registry.
 func Register() {
-	prometheus.MustRegister(InterfaceUp)
-	prometheus.MustRegister(BGPPeers)
-	prometheus.MustRegister(InterfaceSpeedMbps)
-	prometheus.MustRegister(InterfaceDuplex)
-	prometheus.MustRegister(DeviceUptimeSeconds)
-	prometheus.MustRegister(DeviceInfo)
-	prometheus.MustRegister(OSPFNeighbors)
-	prometheus.MustRegister(InterfaceInputErrors)
-	prometheus.MustRegister(InterfaceOutputErrors)
-	prometheus.MustRegister(LLDPNeighbors)
-	prometheus.MustRegister(DeviceMemoryTotal)
-	prometheus.MustRegister(DeviceMemoryFree)
-	prometheus.MustRegister(CPUUsage)
-	prometheus.MustRegister(MemoryUsage)
+	Default.MustRegister(prometheus.DefaultRegisterer)
+	registerExporter(prometheus.DefaultRegisterer)
 }
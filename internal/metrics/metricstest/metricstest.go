@@ -0,0 +1,73 @@
+// Package metricstest reads back the series of a metrics.Set in tests.
+package metricstest
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"netmetrics_exporter/internal/metrics"
+)
+
+// Value returns the value of the series of family name whose labels include
+// the given name/value pairs, and whether there is one.
+func Value(t testing.TB, s *metrics.Set, name string, labels ...string) (float64, bool) {
+	t.Helper()
+	for _, m := range series(t, s, name) {
+		if matches(m, labels) {
+			switch {
+			case m.Gauge != nil:
+				return m.Gauge.GetValue(), true
+			case m.Counter != nil:
+				return m.Counter.GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Count returns how many series of family name include the given
+// name/value pairs.
+func Count(t testing.TB, s *metrics.Set, name string, labels ...string) int {
+	t.Helper()
+	n := 0
+	for _, m := range series(t, s, name) {
+		if matches(m, labels) {
+			n++
+		}
+	}
+	return n
+}
+
+func series(t testing.TB, s *metrics.Set, name string) []*dto.Metric {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	s.MustRegister(registry)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.Metric
+		}
+	}
+	return nil
+}
+
+func matches(m *dto.Metric, labels []string) bool {
+	for i := 0; i+1 < len(labels); i += 2 {
+		found := false
+		for _, l := range m.Label {
+			if l.GetName() == labels[i] && l.GetValue() == labels[i+1] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
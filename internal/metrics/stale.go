@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tracker numbers the collection cycles of every device. A series remembers
+// the cycle in which it was last written; anything older than the current
+// cycle when the cycle ends was not refreshed and is removed.
+type tracker struct {
+	mu     sync.Mutex
+	cycles map[string]uint64
+}
+
+func newTracker() *tracker {
+	return &tracker{cycles: make(map[string]uint64)}
+}
+
+func (t *tracker) begin(hostname string) {
+	t.mu.Lock()
+	t.cycles[hostname]++
+	t.mu.Unlock()
+}
+
+func (t *tracker) current(hostname string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cycles[hostname]
+}
+
+func (t *tracker) forget(hostname string) {
+	t.mu.Lock()
+	delete(t.cycles, hostname)
+	t.mu.Unlock()
+}
+
+// trackedVec is a metric vector whose series can be expired per device.
+type trackedVec interface {
+	prometheus.Collector
+
+	// sweep deletes the series of hostname that were last written before
+	// cycle. A cycle of 0 deletes every series of hostname.
+	sweep(hostname string, cycle uint64)
+}
+
+type seriesEntry struct {
+	labels []string
+	cycle  uint64
+}
+
+// GaugeVec is a prometheus.GaugeVec that records which label sets every
+// device cycle wrote, so Set.EndCycle can drop the ones it did not.
+type GaugeVec struct {
+	*prometheus.GaugeVec
+
+	tracker *tracker
+	hostIdx int
+
+	mu     sync.Mutex
+	series map[string]seriesEntry
+}
+
+func newGaugeVec(t *tracker, opts prometheus.GaugeOpts, labelNames []string) *GaugeVec {
+	return &GaugeVec{
+		GaugeVec: prometheus.NewGaugeVec(opts, labelNames),
+		tracker:  t,
+		hostIdx:  hostnameIndex(labelNames),
+		series:   make(map[string]seriesEntry),
+	}
+}
+
+// WithLabelValues returns the gauge for lvs and marks it as refreshed in the
+// current cycle of the device it belongs to.
+func (v *GaugeVec) WithLabelValues(lvs ...string) prometheus.Gauge {
+	v.touch(lvs)
+	return v.GaugeVec.WithLabelValues(lvs...)
+}
+
+// DeleteLabelValues removes the series for lvs, which is then no longer
+// exported even before its device's cycle ends.
+func (v *GaugeVec) DeleteLabelValues(lvs ...string) bool {
+	v.mu.Lock()
+	delete(v.series, strings.Join(lvs, "\xff"))
+	v.mu.Unlock()
+	return v.GaugeVec.DeleteLabelValues(lvs...)
+}
+
+func (v *GaugeVec) touch(lvs []string) {
+	cycle := v.tracker.current(lvs[v.hostIdx])
+	key := strings.Join(lvs, "\xff")
+
+	v.mu.Lock()
+	v.series[key] = seriesEntry{labels: append([]string(nil), lvs...), cycle: cycle}
+	v.mu.Unlock()
+}
+
+func (v *GaugeVec) sweep(hostname string, cycle uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for key, s := range v.series {
+		if s.labels[v.hostIdx] != hostname {
+			continue
+		}
+		if cycle == 0 || s.cycle < cycle {
+			v.GaugeVec.DeleteLabelValues(s.labels...)
+			delete(v.series, key)
+		}
+	}
+}
+
+// CounterVec exports running totals read from devices, such as interface
+// octets, as Prometheus counters. Devices report the total rather than an
+// increment, so series are set, not added to; a device reboot resets them
+// like any counter reset. Series expire like those of a GaugeVec.
+type CounterVec struct {
+	desc    *prometheus.Desc
+	tracker *tracker
+	hostIdx int
+
+	mu     sync.Mutex
+	series map[string]*counterEntry
+}
+
+type counterEntry struct {
+	seriesEntry
+	value float64
+}
+
+// Counter is one series of a CounterVec.
+type Counter struct {
+	entry *counterEntry
+	mu    *sync.Mutex
+}
+
+// Set records the total reported by the device.
+func (c Counter) Set(v float64) {
+	c.mu.Lock()
+	c.entry.value = v
+	c.mu.Unlock()
+}
+
+func newCounterVec(t *tracker, opts prometheus.CounterOpts, labelNames []string) *CounterVec {
+	return &CounterVec{
+		desc:    prometheus.NewDesc(opts.Name, opts.Help, labelNames, opts.ConstLabels),
+		tracker: t,
+		hostIdx: hostnameIndex(labelNames),
+		series:  make(map[string]*counterEntry),
+	}
+}
+
+// WithLabelValues returns the counter for lvs and marks it as refreshed in
+// the current cycle of the device it belongs to.
+func (v *CounterVec) WithLabelValues(lvs ...string) Counter {
+	cycle := v.tracker.current(lvs[v.hostIdx])
+	key := strings.Join(lvs, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	e, ok := v.series[key]
+	if !ok {
+		e = &counterEntry{seriesEntry: seriesEntry{labels: append([]string(nil), lvs...)}}
+		v.series[key] = e
+	}
+	e.cycle = cycle
+	return Counter{entry: e, mu: &v.mu}
+}
+
+func (v *CounterVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+func (v *CounterVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, e := range v.series {
+		ch <- prometheus.MustNewConstMetric(v.desc, prometheus.CounterValue, e.value, e.labels...)
+	}
+}
+
+func (v *CounterVec) sweep(hostname string, cycle uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for key, e := range v.series {
+		if e.labels[v.hostIdx] != hostname {
+			continue
+		}
+		if cycle == 0 || e.cycle < cycle {
+			delete(v.series, key)
+		}
+	}
+}
+
+func hostnameIndex(labelNames []string) int {
+	for i, name := range labelNames {
+		if name == "hostname" {
+			return i
+		}
+	}
+	panic("metrics: tracked vector has no hostname label")
+}
+
+// BeginCycle starts a new collection cycle for hostname. Every series the
+// collector writes until EndCycle is considered fresh.
+func (s *Set) BeginCycle(hostname string) {
+	s.tracker.begin(hostname)
+}
+
+// EndCycle removes the series of hostname that the cycle did not refresh:
+// deleted interfaces, renamed subinterfaces, vanished peers and old label
+// values such as a previous duplex mode.
+func (s *Set) EndCycle(hostname string) {
+	cycle := s.tracker.current(hostname)
+	for _, v := range s.vecs() {
+		v.sweep(hostname, cycle)
+	}
+}
+
+// DeleteDevice removes every series of hostname, including its health
+// series, for devices that left the inventory.
+func (s *Set) DeleteDevice(hostname string) {
+	for _, v := range s.vecs() {
+		v.sweep(hostname, 0)
+	}
+	s.health.deleteDevice(hostname)
+	s.tracker.forget(hostname)
+}
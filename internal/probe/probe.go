@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"netmetrics_exporter/internal/collector"
+	"netmetrics_exporter/internal/inventory"
+	"netmetrics_exporter/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// LookupFunc resolves the target query parameter to an inventory device.
+type LookupFunc func(target string) (inventory.Device, bool)
+
+// Handler serves /probe?target=<host>&module=<vendor>. Every request polls
+// the target synchronously and answers with a registry that only holds that
+// device's metrics, in the style of snmp_exporter and blackbox_exporter.
+type Handler struct {
+	Lookup LookupFunc
+
+	// Timeout bounds a probe when Prometheus does not announce its own
+	// scrape timeout.
+	Timeout time.Duration
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		return
+	}
+
+	dev, ok := h.Lookup(target)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown target %q", target), http.StatusNotFound)
+		return
+	}
+
+	// The module overrides the vendor from the inventory, so the same host
+	// can be probed through a different collector. "vendor:protocol" also
+	// picks the protocol; a bare vendor uses its default one.
+	if module := r.URL.Query().Get("module"); module != "" {
+		vendor, protocol, _ := strings.Cut(module, ":")
+		dev.Vendor, dev.Protocol = inventory.NormalizeVendor(vendor), protocol
+	}
+	c, err := collector.For(dev)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unknown module %q: %v", dev.Vendor, err), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout(r))
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	set := metrics.NewSet()
+	set.MustRegister(registry)
+
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "netmetrics_probe_success",
+		Help: "Whether the probe of the target succeeded (1) or failed (0).",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "netmetrics_probe_duration_seconds",
+		Help: "How long the probe took to complete in seconds.",
+	})
+	registry.MustRegister(probeSuccess, probeDuration)
+
+	start := time.Now()
+	err = c.Collect(ctx, dev, set)
+	set.ObserveDevice(dev.Hostname, dev.Vendor, start, err)
+	if err != nil {
+		log.Printf("[ERROR] probe %s (%s): %v", dev.Hostname, dev.Vendor, err)
+	} else {
+		probeSuccess.Set(1)
+	}
+	probeDuration.Set(time.Since(start).Seconds())
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// timeout honours the X-Prometheus-Scrape-Timeout-Seconds header, leaving a
+// little headroom so the response still reaches Prometheus in time. Very
+// short scrape timeouts keep half their value rather than the headroom.
+func (h *Handler) timeout(r *http.Request) time.Duration {
+	timeout := h.Timeout
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+			scrape := time.Duration(secs * float64(time.Second))
+			timeout = scrape - 500*time.Millisecond
+			if timeout < scrape/2 {
+				timeout = scrape / 2
+			}
+		}
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return timeout
+}
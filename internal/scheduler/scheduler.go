@@ -0,0 +1,254 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"netmetrics_exporter/internal/inventory"
+)
+
+// PollFunc collects metrics for a single device. The context carries the
+// per-device timeout and is cancelled when the scheduler shuts down.
+type PollFunc func(ctx context.Context, device inventory.Device) error
+
+// Config controls how often and how widely devices are polled. Interval and
+// Timeout are defaults; a device can override both through its inventory
+// entry.
+type Config struct {
+	Workers  int
+	Interval time.Duration
+	Jitter   time.Duration
+	Timeout  time.Duration
+
+	// OnStop, if set, is called once the poller of a device has exited and
+	// none of its polls are running any more: when the device left the
+	// inventory, changed, or the scheduler shut down.
+	OnStop func(device inventory.Device)
+}
+
+// Scheduler polls every device on its own timer and runs at most
+// Config.Workers polls at the same time. The device set can be changed at
+// any time with Update.
+type Scheduler struct {
+	cfg   Config
+	poll  PollFunc
+	slots chan struct{}
+
+	mu      sync.Mutex
+	ctx     context.Context
+	devices []inventory.Device
+	pollers map[string]*poller
+	wg      sync.WaitGroup
+}
+
+// poller is the polling loop of one device.
+type poller struct {
+	dev    inventory.Device
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(cfg Config, poll PollFunc) *Scheduler {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Timeout <= 0 || cfg.Timeout > cfg.Interval {
+		cfg.Timeout = cfg.Interval
+	}
+	return &Scheduler{
+		cfg:     cfg,
+		poll:    poll,
+		slots:   make(chan struct{}, cfg.Workers),
+		pollers: make(map[string]*poller),
+	}
+}
+
+// Run polls the devices passed to Update until ctx is cancelled, then waits
+// for every poller to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.sync()
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	for host, p := range s.pollers {
+		p.cancel()
+		delete(s.pollers, host)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Update replaces the set of polled devices. Pollers of unchanged devices
+// keep running, so their timers and metrics are not disturbed; removed
+// devices are stopped and new or modified ones are started.
+func (s *Scheduler) Update(devices []inventory.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices = devices
+	if s.ctx != nil && s.ctx.Err() == nil {
+		s.sync()
+	}
+}
+
+// sync starts and stops pollers until they match s.devices. s.mu must be
+// held.
+func (s *Scheduler) sync() {
+	wanted := make(map[string]inventory.Device, len(s.devices))
+	for _, dev := range s.devices {
+		wanted[dev.Hostname] = dev
+	}
+
+	stopped := make(map[string]*poller)
+	for host, p := range s.pollers {
+		if dev, ok := wanted[host]; ok && reflect.DeepEqual(dev, p.dev) {
+			continue
+		}
+		log.Printf("[INFO] Stopping poller for %s", host)
+		p.cancel()
+		delete(s.pollers, host)
+		stopped[host] = p
+	}
+
+	for host, dev := range wanted {
+		if _, ok := s.pollers[host]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(s.ctx)
+		p := &poller{dev: dev, cancel: cancel, done: make(chan struct{})}
+		s.pollers[host] = p
+
+		// A modified device must not start polling before its old poller
+		// (and OnStop) are done, or the two would share its series.
+		prev := stopped[host]
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer close(p.done)
+			if prev != nil {
+				<-prev.done
+			}
+			s.loop(ctx, p.dev)
+			if s.cfg.OnStop != nil {
+				s.cfg.OnStop(p.dev)
+			}
+		}()
+	}
+}
+
+// loop polls a single device until ctx is cancelled. A poll that outlives
+// its timeout keeps running in the background, but the next poll of the same
+// device is skipped until it has returned, so polls never overlap.
+func (s *Scheduler) loop(ctx context.Context, dev inventory.Device) {
+	interval, timeout := s.intervalFor(dev), s.timeoutFor(dev)
+
+	// Spread the first round over the whole interval so a large inventory
+	// does not hit the worker pool all at once.
+	timer := time.NewTimer(randDuration(interval))
+	defer timer.Stop()
+
+	var inflight chan struct{}
+	defer func() {
+		if inflight != nil {
+			<-inflight
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if inflight != nil {
+			select {
+			case <-inflight:
+				inflight = nil
+			default:
+				log.Printf("[WARN] %s: previous poll still running, skipping this round", dev.Hostname)
+				timer.Reset(s.next(interval))
+				continue
+			}
+		}
+
+		inflight = s.dispatch(ctx, dev, timeout)
+		timer.Reset(s.next(interval))
+	}
+}
+
+// dispatch waits for a free worker, runs one poll and returns a channel that
+// is closed once the poll function has actually returned. The worker stays
+// taken until then, even after the timeout, so a poll that ignores its
+// context still counts against the limit.
+func (s *Scheduler) dispatch(ctx context.Context, dev inventory.Device, timeout time.Duration) chan struct{} {
+	done := make(chan struct{})
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		close(done)
+		return done
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(done)
+		defer func() { <-s.slots }()
+		errc <- s.poll(pollCtx, dev)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-pollCtx.Done():
+		err = pollCtx.Err()
+	}
+	cancel()
+
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[ERROR] %s (%s): %v", dev.Hostname, dev.Vendor, err)
+	}
+	return done
+}
+
+func (s *Scheduler) next(interval time.Duration) time.Duration {
+	return interval + randDuration(s.cfg.Jitter)
+}
+
+func (s *Scheduler) intervalFor(dev inventory.Device) time.Duration {
+	if dev.Interval > 0 {
+		return dev.Interval
+	}
+	return s.cfg.Interval
+}
+
+func (s *Scheduler) timeoutFor(dev inventory.Device) time.Duration {
+	timeout := s.cfg.Timeout
+	if dev.Timeout > 0 {
+		timeout = dev.Timeout
+	}
+	if interval := s.intervalFor(dev); timeout > interval {
+		timeout = interval
+	}
+	return timeout
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}